@@ -0,0 +1,116 @@
+package bandit
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Agent adapts a contextual bandit Model to the iface.Agenter interface.
+//
+// The features of a state are derived from the state's Features method (see
+// iface.Featurer). Each of the state's possible actions is treated as an arm
+// of the bandit. Since a contextual bandit has no notion of future rewards,
+// Learn ignores the state that results from a transition.
+type Agent struct {
+	TieBreaker func(int) int
+	rng        *rand.Rand
+	model      Model
+}
+
+// Option configures an Agent.
+type Option func(*Agent)
+
+// WithSeed seeds the random source from which the Agent breaks ties, making
+// its recommendations reproducible. By default, the source is seeded from the
+// current time.
+func WithSeed(seed int64) Option {
+	return func(a *Agent) {
+		a.rng = rand.New(rand.NewSource(seed))
+	}
+}
+
+// NewAgent returns a reference to a new Agent that makes recommendations
+// using the supplied model, configured by the supplied options.
+func NewAgent(model Model, opts ...Option) *Agent {
+	a := &Agent{
+		rng:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		model: model,
+	}
+	a.TieBreaker = func(n int) int {
+		return a.rng.Intn(n)
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// RecommendAction recommends the action with the greatest upper confidence
+// bound for the given state. If two or more actions share the greatest upper
+// confidence bound, the action is chosen at random.
+// An error is returned if the state does not implement iface.Featurer.
+func (a *Agent) RecommendAction(state iface.Stater) (iface.Actioner, error) {
+	features, err := getFeatures(state)
+	if err != nil {
+		return nil, err
+	}
+
+	bestActions := []iface.Actioner{}
+	bestValue := -1 * math.MaxFloat64
+	for _, action := range state.PossibleActions() {
+		value, err := a.model.UCB(action.ID(), features)
+		if err != nil {
+			return nil, err
+		}
+		if value > bestValue {
+			bestActions = []iface.Actioner{action}
+			bestValue = value
+		} else if value == bestValue {
+			bestActions = append(bestActions, action)
+		}
+	}
+
+	if len(bestActions) == 0 {
+		return nil, fmt.Errorf("state '%v' reports no possible actions", state.ID())
+	}
+	return bestActions[a.TieBreaker(len(bestActions))], nil
+}
+
+// Transition applies an action to a given state.
+func (a *Agent) Transition(currentState iface.Stater, action iface.Actioner) error {
+	if !currentState.ActionIsCompatible(action) {
+		return fmt.Errorf("action %v is not compatible with state %v", action.ID(), currentState.ID())
+	}
+	return currentState.Apply(action)
+}
+
+// Learn updates the model with the reward observed after taking an action
+// from previousState. If no action has been previously taken, or there is no
+// previous state, Learn is a no-op. Learn will panic if previousState does not
+// implement iface.Featurer, or if the model rejects the observation.
+func (a *Agent) Learn(previousState iface.Stater, actionTaken iface.Actioner, currentState iface.Stater, reward float64) {
+	if previousState == nil || actionTaken == nil {
+		return
+	}
+	features, err := getFeatures(previousState)
+	if err != nil {
+		panic(err)
+	}
+	if err := a.model.Update(actionTaken.ID(), features, reward); err != nil {
+		panic(err)
+	}
+}
+
+func getFeatures(state iface.Stater) ([]float64, error) {
+	featurer, ok := state.(iface.Featurer)
+	if !ok {
+		return nil, fmt.Errorf("state '%v' does not provide features", state.ID())
+	}
+	return featurer.Features(), nil
+}
+
+var _ iface.Agenter = (*Agent)(nil)
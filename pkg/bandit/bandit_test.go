@@ -0,0 +1,199 @@
+package bandit_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/bandit"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type testAction string
+
+func (a testAction) ID() string { return string(a) }
+
+type testState struct {
+	features []float64
+	actions  []iface.Actioner
+}
+
+func (s *testState) PossibleActions() []iface.Actioner      { return s.actions }
+func (s *testState) ActionIsCompatible(iface.Actioner) bool { return true }
+func (s *testState) ID() string                             { return fmt.Sprint(s.features) }
+func (s *testState) Apply(iface.Actioner) error             { return nil }
+func (s *testState) Features() []float64                    { return s.features }
+func (s *testState) GetAction(id string) (iface.Actioner, error) {
+	return testAction(id), nil
+}
+
+// train teaches the model that arm A pays out when the first feature is set,
+// and arm B pays out when the second feature is set.
+func train(t *testing.T, model bandit.Model) {
+	contexts := [][]float64{{1, 0}, {0, 1}}
+	for i := 0; i < 50; i++ {
+		for _, x := range contexts {
+			if err := model.Update("A", x, x[0]); err != nil {
+				t.Fatal(err)
+			}
+			if err := model.Update("B", x, x[1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func Test_AgentRecommendAction(t *testing.T) {
+	testCases := []struct {
+		name  string
+		model bandit.Model
+	}{
+		{"disjoint", bandit.NewDisjointLinUCB(2, 0.1)},
+		{"hybrid", bandit.NewHybridLinUCB(2, 2, 0.1)},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			train(t, testCase.model)
+			a := bandit.NewAgent(testCase.model)
+			actions := []iface.Actioner{testAction("A"), testAction("B")}
+
+			action, err := a.RecommendAction(&testState{[]float64{1, 0}, actions})
+			if assert.NoError(t, err) {
+				assert.Equal(t, "A", action.ID())
+			}
+
+			action, err = a.RecommendAction(&testState{[]float64{0, 1}, actions})
+			if assert.NoError(t, err) {
+				assert.Equal(t, "B", action.ID())
+			}
+		})
+	}
+}
+
+func Test_AgentRecommendActionRequiresFeatures(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	state := agent.NewMockStater(mc)
+	state.EXPECT().ID().Return("A").AnyTimes()
+
+	a := bandit.NewAgent(bandit.NewDisjointLinUCB(2, 1))
+	_, err := a.RecommendAction(state)
+	assert.EqualError(t, err, "state 'A' does not provide features")
+}
+
+func Test_AgentLearn(t *testing.T) {
+	model := bandit.NewDisjointLinUCB(2, 0)
+	a := bandit.NewAgent(model)
+	state := &testState{[]float64{1, 0}, nil}
+
+	a.Learn(nil, testAction("A"), state, 1)
+	assert.Equal(t, []float64{0, 0}, model.Coefficients("A"))
+
+	a.Learn(state, testAction("A"), state, 1)
+	assert.Equal(t, []float64{0.5, 0}, model.Coefficients("A"))
+}
+
+func Test_DimensionMismatch(t *testing.T) {
+	_, err := bandit.NewDisjointLinUCB(2, 1).UCB("A", []float64{1})
+	assert.EqualError(t, err, "expected 2 features, got 1")
+
+	err = bandit.NewHybridLinUCB(3, 2, 1).HybridUpdate("A", []float64{1}, []float64{1, 2}, 1)
+	assert.EqualError(t, err, "shared features: expected 3 features, got 1")
+}
+
+func Test_DisjointContextRoundTrip(t *testing.T) {
+	original := bandit.NewDisjointLinUCB(2, 0.1)
+	train(t, original)
+
+	data, err := json.Marshal(original.GetAgentContext())
+	if !assert.NoError(t, err) {
+		return
+	}
+	var c bandit.DisjointContext
+	if !assert.NoError(t, json.Unmarshal(data, &c)) {
+		return
+	}
+
+	restored := bandit.NewDisjointLinUCB(0, 0)
+	if !assert.NoError(t, restored.SetAgentContext(c)) {
+		return
+	}
+	for _, arm := range []string{"A", "B"} {
+		exp, _ := original.UCB(arm, []float64{0.3, 0.7})
+		act, _ := restored.UCB(arm, []float64{0.3, 0.7})
+		assert.Equal(t, exp, act)
+	}
+}
+
+func Test_HybridContextRoundTrip(t *testing.T) {
+	original := bandit.NewHybridLinUCB(2, 2, 0.1)
+	train(t, original)
+
+	data, err := json.Marshal(original.GetAgentContext())
+	if !assert.NoError(t, err) {
+		return
+	}
+	var c bandit.HybridContext
+	if !assert.NoError(t, json.Unmarshal(data, &c)) {
+		return
+	}
+
+	restored := bandit.NewHybridLinUCB(0, 0, 0)
+	if !assert.NoError(t, restored.SetAgentContext(c)) {
+		return
+	}
+	for _, arm := range []string{"A", "B"} {
+		exp, _ := original.UCB(arm, []float64{0.3, 0.7})
+		act, _ := restored.UCB(arm, []float64{0.3, 0.7})
+		assert.InDelta(t, exp, act, 1e-9)
+	}
+}
+
+func Test_ScoringDoesNotAddArms(t *testing.T) {
+	disjoint := bandit.NewDisjointLinUCB(2, 0.1)
+	_, err := disjoint.UCB("A", []float64{1, 0})
+	assert.NoError(t, err)
+	disjoint.Coefficients("B")
+	assert.Empty(t, disjoint.GetAgentContext().Arms)
+
+	hybrid := bandit.NewHybridLinUCB(2, 2, 0.1)
+	_, err = hybrid.HybridUCB("A", []float64{1, 0}, []float64{0, 1})
+	assert.NoError(t, err)
+	assert.Empty(t, hybrid.GetAgentContext().Arms)
+
+	assert.NoError(t, hybrid.Update("A", []float64{1, 0}, 1))
+	assert.Len(t, hybrid.GetAgentContext().Arms, 1)
+}
+
+func Test_SetAgentContextRejectsBadDimensions(t *testing.T) {
+	err := bandit.NewDisjointLinUCB(2, 1).SetAgentContext(bandit.DisjointContext{
+		Dimensions: 2,
+		Arms: map[string]bandit.ArmContext{
+			"A": {AInv: [][]float64{{1}}, B: []float64{0}},
+		},
+	})
+	assert.EqualError(t, err, "arm 'A' does not have 2 dimensions")
+}
+
+func Test_AgentSeed(t *testing.T) {
+	actions := []iface.Actioner{testAction("A"), testAction("B"), testAction("C"), testAction("D")}
+	state := &testState{[]float64{1}, actions}
+	recommendations := func() []string {
+		a := bandit.NewAgent(bandit.NewDisjointLinUCB(1, 1), bandit.WithSeed(7))
+		ids := []string{}
+		for i := 0; i < 20; i++ {
+			action, err := a.RecommendAction(state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, action.ID())
+		}
+		return ids
+	}
+	assert.Equal(t, recommendations(), recommendations())
+}
@@ -0,0 +1,179 @@
+package bandit
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/internal/linalg"
+)
+
+// Model is anything that can score an arm for a given feature vector and learn
+// from the reward observed after that arm has been pulled.
+type Model interface {
+	// UCB returns the upper confidence bound of the expected reward for
+	// pulling the specified arm in the context described by features.
+	UCB(arm string, features []float64) (float64, error)
+
+	// Update records the reward observed after pulling the specified arm in
+	// the context described by features.
+	Update(arm string, features []float64, reward float64) error
+}
+
+// DisjointLinUCB is an implementation of the LinUCB algorithm with disjoint
+// linear models, in which each arm learns its own set of coefficients
+// independently of every other arm.
+//
+// Each arm maintains the inverse of its design matrix, which is updated in
+// place via the Sherman-Morrison formula as observations are recorded. Thus no
+// matrix inversion is required during normal operation.
+//
+// see http://rob.schapire.net/papers/www10.pdf (Algorithm 1)
+type DisjointLinUCB struct {
+	alpha      float64
+	dimensions int
+	arms       map[string]*disjointArm
+}
+
+type disjointArm struct {
+	aInv *linalg.Matrix
+	b    []float64
+}
+
+// NewDisjointLinUCB returns a reference to a new DisjointLinUCB.
+//
+// dimensions:
+//
+//	The length of the feature vectors that will be supplied to the model.
+//
+// alpha:
+//
+//	Controls the width of the confidence bound, and thus the degree to which
+//	the model explores arms whose reward is uncertain. Larger values result in
+//	more exploration.
+func NewDisjointLinUCB(dimensions int, alpha float64) *DisjointLinUCB {
+	return &DisjointLinUCB{
+		alpha:      alpha,
+		dimensions: dimensions,
+		arms:       make(map[string]*disjointArm),
+	}
+}
+
+// UCB returns the upper confidence bound of the expected reward for pulling
+// the specified arm in the context described by features. Arms that have not
+// been previously observed are scored as if no data has been recorded.
+func (l *DisjointLinUCB) UCB(arm string, features []float64) (float64, error) {
+	if err := checkDimensions(l.dimensions, features); err != nil {
+		return 0, err
+	}
+	a := l.getArm(arm)
+	theta := a.aInv.MulVec(a.b)
+	variance := linalg.QuadForm(features, a.aInv, features)
+	return linalg.Dot(theta, features) + l.alpha*math.Sqrt(math.Max(variance, 0)), nil
+}
+
+// Update records the reward observed after pulling the specified arm in the
+// context described by features.
+func (l *DisjointLinUCB) Update(arm string, features []float64, reward float64) error {
+	if err := checkDimensions(l.dimensions, features); err != nil {
+		return err
+	}
+	a := l.getArm(arm)
+	if err := linalg.ShermanMorrison(a.aInv, features, features); err != nil {
+		return err
+	}
+	linalg.AddVec(a.b, features, reward)
+	l.arms[arm] = a
+	return nil
+}
+
+// Coefficients returns the coefficients that the model has learned thus far
+// for the specified arm.
+func (l *DisjointLinUCB) Coefficients(arm string) []float64 {
+	a := l.getArm(arm)
+	return a.aInv.MulVec(a.b)
+}
+
+// getArm returns the specified arm, or an arm that has not recorded any data
+// if the arm has not been previously observed. New arms are not added to the
+// model; Update does so once an observation has been recorded.
+func (l *DisjointLinUCB) getArm(arm string) *disjointArm {
+	a, found := l.arms[arm]
+	if !found {
+		a = &disjointArm{
+			aInv: linalg.Identity(l.dimensions),
+			b:    make([]float64, l.dimensions),
+		}
+	}
+	return a
+}
+
+// ArmContext describes the learned parameters of a single arm.
+type ArmContext struct {
+	// AInv is the inverse of the arm's design matrix.
+	AInv [][]float64
+	// B is the arm's reward-weighted sum of feature vectors.
+	B []float64
+	// SharedB is only used by HybridLinUCB, and holds the arm's
+	// cross-covariance with the shared features.
+	SharedB [][]float64 `json:",omitempty"`
+}
+
+// DisjointContext provides information about the internal conditions of a
+// DisjointLinUCB.
+type DisjointContext struct {
+	Alpha      float64
+	Dimensions int
+	Arms       map[string]ArmContext
+}
+
+// GetAgentContext provides information about the internal conditions of the
+// model. It is intended to allow the per-arm matrices to be serialized
+// without exposing fields that should remain private.
+func (l *DisjointLinUCB) GetAgentContext() DisjointContext {
+	c := DisjointContext{
+		Alpha:      l.alpha,
+		Dimensions: l.dimensions,
+		Arms:       make(map[string]ArmContext, len(l.arms)),
+	}
+	for id, a := range l.arms {
+		b := make([]float64, len(a.b))
+		copy(b, a.b)
+		c.Arms[id] = ArmContext{
+			AInv: a.aInv.ToRows(),
+			B:    b,
+		}
+	}
+	return c
+}
+
+// SetAgentContext sets the internal conditions of the model based on a
+// pre-existing DisjointContext. An error is returned if the dimensions of any
+// arm are inconsistent with the context's Dimensions.
+func (l *DisjointLinUCB) SetAgentContext(c DisjointContext) error {
+	arms := make(map[string]*disjointArm, len(c.Arms))
+	for id, ac := range c.Arms {
+		aInv, err := linalg.FromRows(ac.AInv)
+		if err != nil {
+			return err
+		}
+		if aInv.Rows != c.Dimensions || aInv.Cols != c.Dimensions || len(ac.B) != c.Dimensions {
+			return fmt.Errorf("arm '%v' does not have %v dimensions", id, c.Dimensions)
+		}
+		b := make([]float64, len(ac.B))
+		copy(b, ac.B)
+		arms[id] = &disjointArm{aInv: aInv, b: b}
+	}
+	l.alpha = c.Alpha
+	l.dimensions = c.Dimensions
+	l.arms = arms
+	return nil
+}
+
+func checkDimensions(expected int, features []float64) error {
+	if len(features) != expected {
+		return fmt.Errorf("expected %v features, got %v", expected, len(features))
+	}
+	return nil
+}
+
+var _ Model = (*DisjointLinUCB)(nil)
@@ -0,0 +1,4 @@
+// Package bandit provides implementations of contextual bandit models, in which
+// each decision is described by a vector of features rather than a discrete
+// state ID.
+package bandit
@@ -0,0 +1,268 @@
+package bandit
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/internal/linalg"
+)
+
+// HybridLinUCB is an implementation of the LinUCB algorithm with hybrid linear
+// models. In addition to the coefficients that each arm learns independently,
+// a set of coefficients is shared by every arm. This allows what is learned
+// about one arm to inform the expected reward of other arms, which is
+// particularly helpful when new arms are frequently introduced.
+//
+// Per-arm design matrices are maintained as inverses and updated via the
+// Sherman-Morrison formula. The shared design matrix is not a rank-one update,
+// so it is re-inverted after each observation. The shared dimensions are
+// expected to be small.
+//
+// see http://rob.schapire.net/papers/www10.pdf (Algorithm 2)
+type HybridLinUCB struct {
+	alpha            float64
+	sharedDimensions int
+	armDimensions    int
+	a0               *linalg.Matrix
+	a0Inv            *linalg.Matrix
+	b0               []float64
+	arms             map[string]*hybridArm
+}
+
+type hybridArm struct {
+	aInv    *linalg.Matrix
+	sharedB *linalg.Matrix
+	b       []float64
+}
+
+// NewHybridLinUCB returns a reference to a new HybridLinUCB.
+//
+// sharedDimensions:
+//
+//	The length of the feature vectors whose coefficients are shared by all
+//	arms.
+//
+// armDimensions:
+//
+//	The length of the feature vectors whose coefficients are learned
+//	independently by each arm.
+//
+// alpha:
+//
+//	Controls the width of the confidence bound, and thus the degree to which
+//	the model explores arms whose reward is uncertain. Larger values result in
+//	more exploration.
+func NewHybridLinUCB(sharedDimensions, armDimensions int, alpha float64) *HybridLinUCB {
+	return &HybridLinUCB{
+		alpha:            alpha,
+		sharedDimensions: sharedDimensions,
+		armDimensions:    armDimensions,
+		a0:               linalg.Identity(sharedDimensions),
+		a0Inv:            linalg.Identity(sharedDimensions),
+		b0:               make([]float64, sharedDimensions),
+		arms:             make(map[string]*hybridArm),
+	}
+}
+
+// HybridUCB returns the upper confidence bound of the expected reward for
+// pulling the specified arm, where shared describes the features whose
+// coefficients are shared by all arms, and features describes the features
+// whose coefficients are specific to the arm.
+func (h *HybridLinUCB) HybridUCB(arm string, shared, features []float64) (float64, error) {
+	if err := h.checkDimensions(shared, features); err != nil {
+		return 0, err
+	}
+	a := h.getArm(arm)
+
+	beta := h.a0Inv.MulVec(h.b0)
+	residual := make([]float64, len(a.b))
+	copy(residual, a.b)
+	linalg.AddVec(residual, a.sharedB.MulVec(beta), -1)
+	theta := a.aInv.MulVec(residual)
+
+	// aInvX = A_a^-1 x, and a0InvBtAInvX = A_0^-1 B_a^T A_a^-1 x
+	aInvX := a.aInv.MulVec(features)
+	a0InvBtAInvX := h.a0Inv.MulVec(a.sharedB.T().MulVec(aInvX))
+	variance := linalg.QuadForm(shared, h.a0Inv, shared) -
+		2*linalg.Dot(shared, a0InvBtAInvX) +
+		linalg.Dot(features, aInvX) +
+		linalg.Dot(aInvX, a.sharedB.MulVec(a0InvBtAInvX))
+
+	mean := linalg.Dot(shared, beta) + linalg.Dot(features, theta)
+	return mean + h.alpha*math.Sqrt(math.Max(variance, 0)), nil
+}
+
+// HybridUpdate records the reward observed after pulling the specified arm.
+// See HybridUCB for a description of shared and features.
+func (h *HybridLinUCB) HybridUpdate(arm string, shared, features []float64, reward float64) error {
+	if err := h.checkDimensions(shared, features); err != nil {
+		return err
+	}
+	a := h.getArm(arm)
+
+	// The update is applied to copies so that the model is left unchanged if
+	// any step fails.
+	a0 := h.a0.Clone()
+	b0 := cloneVec(h.b0)
+	updated := &hybridArm{
+		aInv:    a.aInv.Clone(),
+		sharedB: a.sharedB.Clone(),
+		b:       cloneVec(a.b),
+	}
+
+	btAInv := updated.sharedB.T().Mul(updated.aInv)
+	a0.Add(btAInv.Mul(updated.sharedB))
+	linalg.AddVec(b0, btAInv.MulVec(updated.b), 1)
+
+	if err := linalg.ShermanMorrison(updated.aInv, features, features); err != nil {
+		return err
+	}
+	updated.sharedB.Add(linalg.Outer(features, shared))
+	linalg.AddVec(updated.b, features, reward)
+
+	btAInv = updated.sharedB.T().Mul(updated.aInv)
+	a0.Add(linalg.Outer(shared, shared))
+	a0.Sub(btAInv.Mul(updated.sharedB))
+	linalg.AddVec(b0, shared, reward)
+	linalg.AddVec(b0, btAInv.MulVec(updated.b), -1)
+
+	a0Inv, err := linalg.Inverse(a0)
+	if err != nil {
+		return err
+	}
+	h.a0 = a0
+	h.a0Inv = a0Inv
+	h.b0 = b0
+	h.arms[arm] = updated
+	return nil
+}
+
+// UCB satisfies the Model interface by treating features as both the shared
+// and the arm-specific features. This is only possible if the model's shared
+// and arm dimensions are equal.
+func (h *HybridLinUCB) UCB(arm string, features []float64) (float64, error) {
+	return h.HybridUCB(arm, features, features)
+}
+
+// Update satisfies the Model interface by treating features as both the
+// shared and the arm-specific features. This is only possible if the model's
+// shared and arm dimensions are equal.
+func (h *HybridLinUCB) Update(arm string, features []float64, reward float64) error {
+	return h.HybridUpdate(arm, features, features, reward)
+}
+
+// getArm returns the specified arm, or an arm that has not recorded any data
+// if the arm has not been previously observed. New arms are not added to the
+// model; HybridUpdate does so once an observation has been recorded.
+func (h *HybridLinUCB) getArm(arm string) *hybridArm {
+	a, found := h.arms[arm]
+	if !found {
+		a = &hybridArm{
+			aInv:    linalg.Identity(h.armDimensions),
+			sharedB: linalg.NewMatrix(h.armDimensions, h.sharedDimensions),
+			b:       make([]float64, h.armDimensions),
+		}
+	}
+	return a
+}
+
+func cloneVec(v []float64) []float64 {
+	c := make([]float64, len(v))
+	copy(c, v)
+	return c
+}
+
+func (h *HybridLinUCB) checkDimensions(shared, features []float64) error {
+	if err := checkDimensions(h.sharedDimensions, shared); err != nil {
+		return fmt.Errorf("shared features: %v", err)
+	}
+	return checkDimensions(h.armDimensions, features)
+}
+
+// HybridContext provides information about the internal conditions of a
+// HybridLinUCB.
+type HybridContext struct {
+	Alpha            float64
+	SharedDimensions int
+	ArmDimensions    int
+	// A0 is the shared design matrix.
+	A0 [][]float64
+	// B0 is the reward-weighted sum of shared feature vectors.
+	B0   []float64
+	Arms map[string]ArmContext
+}
+
+// GetAgentContext provides information about the internal conditions of the
+// model. It is intended to allow the shared and per-arm matrices to be
+// serialized without exposing fields that should remain private.
+func (h *HybridLinUCB) GetAgentContext() HybridContext {
+	b0 := make([]float64, len(h.b0))
+	copy(b0, h.b0)
+	c := HybridContext{
+		Alpha:            h.alpha,
+		SharedDimensions: h.sharedDimensions,
+		ArmDimensions:    h.armDimensions,
+		A0:               h.a0.ToRows(),
+		B0:               b0,
+		Arms:             make(map[string]ArmContext, len(h.arms)),
+	}
+	for id, a := range h.arms {
+		b := make([]float64, len(a.b))
+		copy(b, a.b)
+		c.Arms[id] = ArmContext{
+			AInv:    a.aInv.ToRows(),
+			B:       b,
+			SharedB: a.sharedB.ToRows(),
+		}
+	}
+	return c
+}
+
+// SetAgentContext sets the internal conditions of the model based on a
+// pre-existing HybridContext. An error is returned if the dimensions of the
+// shared matrices or of any arm are inconsistent with the context.
+func (h *HybridLinUCB) SetAgentContext(c HybridContext) error {
+	a0, err := linalg.FromRows(c.A0)
+	if err != nil {
+		return err
+	}
+	if a0.Rows != c.SharedDimensions || a0.Cols != c.SharedDimensions || len(c.B0) != c.SharedDimensions {
+		return fmt.Errorf("shared matrices do not have %v dimensions", c.SharedDimensions)
+	}
+	a0Inv, err := linalg.Inverse(a0)
+	if err != nil {
+		return err
+	}
+
+	arms := make(map[string]*hybridArm, len(c.Arms))
+	for id, ac := range c.Arms {
+		aInv, err := linalg.FromRows(ac.AInv)
+		if err != nil {
+			return err
+		}
+		sharedB, err := linalg.FromRows(ac.SharedB)
+		if err != nil {
+			return err
+		}
+		if aInv.Rows != c.ArmDimensions || aInv.Cols != c.ArmDimensions || len(ac.B) != c.ArmDimensions ||
+			sharedB.Rows != c.ArmDimensions || sharedB.Cols != c.SharedDimensions {
+			return fmt.Errorf("arm '%v' does not have %vx%v dimensions", id, c.ArmDimensions, c.SharedDimensions)
+		}
+		b := make([]float64, len(ac.B))
+		copy(b, ac.B)
+		arms[id] = &hybridArm{aInv: aInv, sharedB: sharedB, b: b}
+	}
+
+	b0 := make([]float64, len(c.B0))
+	copy(b0, c.B0)
+	h.alpha = c.Alpha
+	h.sharedDimensions = c.SharedDimensions
+	h.armDimensions = c.ArmDimensions
+	h.a0 = a0
+	h.a0Inv = a0Inv
+	h.b0 = b0
+	h.arms = arms
+	return nil
+}
+
+var _ Model = (*HybridLinUCB)(nil)
@@ -0,0 +1,345 @@
+package dqn
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Config describes the structure and hyperparameters of an Agent.
+type Config struct {
+	// Actions lists the ID of every action the agent can recommend. Each
+	// action corresponds to one output of the network.
+	Actions []string
+	// Inputs is the length of the feature vectors supplied by each state.
+	Inputs int
+	// Hidden lists the size of each hidden layer.
+	Hidden []int
+	// LearningRate is the step size used by the Adam optimizer, and must be
+	// positive.
+	LearningRate float64
+	// DiscountFactor determines the importance of future rewards, and must be
+	// between 0 and 1 inclusive.
+	DiscountFactor float64
+	// Exploration determines the probability of recommending a random action
+	// rather than the best known action, as a function of the number of
+	// transitions the agent has learned from.
+	Exploration qlearning.Schedule
+	// BatchSize is the number of transitions sampled from the replay buffer
+	// at each step.
+	BatchSize int
+	// ReplayCapacity is the maximum number of transitions retained.
+	ReplayCapacity int
+	// WarmUp is the number of transitions that must be collected before any
+	// training occurs.
+	WarmUp int
+	// TargetSync is the number of steps between each synchronization of the
+	// target network with the online network.
+	TargetSync int
+	// Seed seeds every random process used by the agent.
+	Seed int64
+}
+
+// DefaultConfig returns a Config with hyperparameters that are reasonable for
+// small control problems such as CartPole.
+func DefaultConfig(actions []string, inputs int) Config {
+	return Config{
+		Actions:        actions,
+		Inputs:         inputs,
+		Hidden:         []int{64, 64},
+		LearningRate:   1e-3,
+		DiscountFactor: .99,
+		Exploration:    qlearning.ExponentialDecay(1, .999, .05),
+		BatchSize:      32,
+		ReplayCapacity: 10000,
+		WarmUp:         500,
+		TargetSync:     250,
+	}
+}
+
+func (c Config) validate() error {
+	switch {
+	case len(c.Actions) == 0:
+		return fmt.Errorf("at least one action is required")
+	case c.Inputs < 1:
+		return fmt.Errorf("inputs must be positive")
+	case !(c.LearningRate > 0) || math.IsInf(c.LearningRate, 1):
+		return fmt.Errorf("learning rate must be positive and finite, got %v", c.LearningRate)
+	case !(c.DiscountFactor >= 0 && c.DiscountFactor <= 1):
+		return fmt.Errorf("discount factor must be between 0 and 1, got %v", c.DiscountFactor)
+	case c.BatchSize < 1:
+		return fmt.Errorf("batch size must be positive")
+	case c.ReplayCapacity < c.BatchSize:
+		return fmt.Errorf("replay capacity must be at least the batch size")
+	case c.TargetSync < 1:
+		return fmt.Errorf("target sync must be positive")
+	}
+	seen := make(map[string]bool, len(c.Actions))
+	for _, id := range c.Actions {
+		if seen[id] {
+			return fmt.Errorf("action '%v' is listed more than once", id)
+		}
+		seen[id] = true
+	}
+	return c.Exploration.ValidateExploration()
+}
+
+// Agent is a Deep Q-Network agent. It approximates the q-value of every
+// action with a multilayer perceptron that takes a state's features (see
+// iface.Featurer) as input. Training is stabilised with experience replay and
+// a target network that is periodically synchronized with the online network.
+//
+// see https://www.nature.com/articles/nature14236
+type Agent struct {
+	config      Config
+	online      *Network
+	target      *Network
+	replay      *replayBuffer
+	rng         *rand.Rand
+	actionIndex map[string]int
+	steps       int
+}
+
+// NewAgent returns a reference to a new Agent, or an error if the supplied
+// Config is invalid.
+func NewAgent(c Config) (*Agent, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(c.Seed))
+	sizes := append(append([]int{c.Inputs}, c.Hidden...), len(c.Actions))
+	online, err := NewNetwork(rng, c.LearningRate, sizes...)
+	if err != nil {
+		return nil, err
+	}
+	actionIndex := make(map[string]int, len(c.Actions))
+	for i, id := range c.Actions {
+		actionIndex[id] = i
+	}
+	return &Agent{
+		config:      c,
+		online:      online,
+		target:      online.Clone(),
+		replay:      newReplayBuffer(c.ReplayCapacity),
+		rng:         rng,
+		actionIndex: actionIndex,
+	}, nil
+}
+
+// RecommendAction recommends an action for the given state. With probability
+// equal to the current exploration rate, a random possible action is chosen.
+// Otherwise, the possible action with the greatest q-value is chosen.
+func (a *Agent) RecommendAction(state iface.Stater) (iface.Actioner, error) {
+	features, err := a.getFeatures(state)
+	if err != nil {
+		return nil, err
+	}
+	possible := state.PossibleActions()
+	if len(possible) == 0 {
+		return nil, fmt.Errorf("state '%v' reports no possible actions", state.ID())
+	}
+	for _, action := range possible {
+		if _, known := a.actionIndex[action.ID()]; !known {
+			return nil, fmt.Errorf("action '%v' is not known to the agent", action.ID())
+		}
+	}
+
+	if a.rng.Float64() < a.ExplorationRate() {
+		return possible[a.rng.Intn(len(possible))], nil
+	}
+
+	q := a.online.Predict(features)
+	best := possible[0]
+	for _, action := range possible[1:] {
+		if q[a.actionIndex[action.ID()]] > q[a.actionIndex[best.ID()]] {
+			best = action
+		}
+	}
+	return best, nil
+}
+
+// Transition applies an action to a given state.
+func (a *Agent) Transition(currentState iface.Stater, action iface.Actioner) error {
+	if !currentState.ActionIsCompatible(action) {
+		return fmt.Errorf("action %v is not compatible with state %v", action.ID(), currentState.ID())
+	}
+	return currentState.Apply(action)
+}
+
+// Learn records a transition in the replay buffer and, once enough
+// transitions have been collected, trains the online network on a minibatch
+// sampled from the buffer. If currentState implements iface.Terminaler and
+// reports that it is terminal, no future reward is expected from it.
+// If no action has been previously taken, or there is no previous state,
+// Learn is a no-op. Learn will panic if currentState is nil, if either state
+// does not supply features of the expected dimensions, or if actionTaken is
+// not known to the agent.
+func (a *Agent) Learn(previousState iface.Stater, actionTaken iface.Actioner, currentState iface.Stater, reward float64) {
+	if previousState == nil || actionTaken == nil {
+		return
+	}
+
+	if currentState == nil {
+		panic("currentState must not be nil")
+	}
+
+	state, err := a.getFeatures(previousState)
+	if err != nil {
+		panic(err)
+	}
+	nextState, err := a.getFeatures(currentState)
+	if err != nil {
+		panic(err)
+	}
+	action, known := a.actionIndex[actionTaken.ID()]
+	if !known {
+		panic(fmt.Sprintf("action '%v' is not known to the agent", actionTaken.ID()))
+	}
+
+	nextMask := make([]bool, len(a.config.Actions))
+	for _, possible := range currentState.PossibleActions() {
+		if i, known := a.actionIndex[possible.ID()]; known {
+			nextMask[i] = true
+		}
+	}
+	terminal := false
+	if t, ok := currentState.(iface.Terminaler); ok {
+		terminal = t.IsTerminal()
+	}
+
+	a.replay.add(transition{
+		state:     state,
+		action:    action,
+		reward:    reward,
+		nextState: nextState,
+		nextMask:  nextMask,
+		terminal:  terminal,
+	})
+	a.steps++
+
+	if a.replay.len() >= a.config.WarmUp && a.replay.len() >= a.config.BatchSize {
+		a.train()
+	}
+	if a.steps%a.config.TargetSync == 0 {
+		a.target.CopyFrom(a.online)
+	}
+}
+
+func (a *Agent) train() {
+	batch := a.replay.sample(a.rng, a.config.BatchSize)
+	inputs := make([][]float64, len(batch))
+	outputs := make([]int, len(batch))
+	targets := make([]float64, len(batch))
+	for i, t := range batch {
+		inputs[i] = t.state
+		outputs[i] = t.action
+		targets[i] = t.reward
+		if t.terminal {
+			continue
+		}
+		bestNext := math.Inf(-1)
+		for j, q := range a.target.Predict(t.nextState) {
+			if t.nextMask[j] && q > bestNext {
+				bestNext = q
+			}
+		}
+		if !math.IsInf(bestNext, -1) {
+			targets[i] += a.config.DiscountFactor * bestNext
+		}
+	}
+	a.online.Train(inputs, outputs, targets)
+}
+
+// ExplorationRate returns the probability that the agent will currently
+// recommend a random action.
+func (a *Agent) ExplorationRate() float64 {
+	return a.config.Exploration.Rate(a.steps)
+}
+
+// SetExploration replaces the agent's exploration schedule. Setting a
+// constant rate of zero results in a purely greedy agent, which is useful for
+// evaluation.
+func (a *Agent) SetExploration(s qlearning.Schedule) {
+	a.config.Exploration = s
+}
+
+// QValues returns the q-value of every action for the given state, keyed by
+// action ID.
+func (a *Agent) QValues(state iface.Stater) (map[string]float64, error) {
+	features, err := a.getFeatures(state)
+	if err != nil {
+		return nil, err
+	}
+	q := a.online.Predict(features)
+	values := make(map[string]float64, len(q))
+	for i, id := range a.config.Actions {
+		values[id] = q[i]
+	}
+	return values, nil
+}
+
+func (a *Agent) getFeatures(state iface.Stater) ([]float64, error) {
+	featurer, ok := state.(iface.Featurer)
+	if !ok {
+		return nil, fmt.Errorf("state '%v' does not provide features", state.ID())
+	}
+	features := featurer.Features()
+	if len(features) != a.config.Inputs {
+		return nil, fmt.Errorf("state '%v' provided %v features, expected %v", state.ID(), len(features), a.config.Inputs)
+	}
+	return append([]float64(nil), features...), nil
+}
+
+// Weights is the portable, serializable form of an Agent's network.
+type Weights struct {
+	Actions []string
+	Layers  []LayerContext
+}
+
+// SaveWeights writes the weights of the agent's online network to w as JSON.
+func (a *Agent) SaveWeights(w io.Writer) error {
+	return json.NewEncoder(w).Encode(Weights{
+		Actions: a.config.Actions,
+		Layers:  a.online.GetLayers(),
+	})
+}
+
+// LoadWeights reads weights previously written by SaveWeights, and applies
+// them to both the online and target networks. An error is returned if the
+// weights were saved by an agent with different actions or a different
+// network shape.
+func (a *Agent) LoadWeights(r io.Reader) error {
+	var weights Weights
+	if err := json.NewDecoder(r).Decode(&weights); err != nil {
+		return err
+	}
+	if len(weights.Actions) != len(a.config.Actions) {
+		return fmt.Errorf("weights describe %v actions, expected %v", len(weights.Actions), len(a.config.Actions))
+	}
+	for i, id := range weights.Actions {
+		if id != a.config.Actions[i] {
+			return fmt.Errorf("weights describe action '%v' at output %v, expected '%v'", id, i, a.config.Actions[i])
+		}
+	}
+	current := a.online.GetLayers()
+	if len(weights.Layers) != len(current) {
+		return fmt.Errorf("weights describe %v layers, expected %v", len(weights.Layers), len(current))
+	}
+	for i, l := range weights.Layers {
+		if l.Inputs != current[i].Inputs || l.Outputs != current[i].Outputs {
+			return fmt.Errorf("layer %v is %vx%v, expected %vx%v", i, l.Inputs, l.Outputs, current[i].Inputs, current[i].Outputs)
+		}
+	}
+	if err := a.online.SetLayers(weights.Layers); err != nil {
+		return err
+	}
+	a.target = a.online.Clone()
+	return nil
+}
+
+var _ iface.Agenter = (*Agent)(nil)
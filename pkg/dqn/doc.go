@@ -0,0 +1,3 @@
+// Package dqn provides a CPU-only Deep Q-Network agent for state spaces that
+// are too large to be represented by a q-table. No GPU or cgo is required.
+package dqn
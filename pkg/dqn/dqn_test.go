@@ -0,0 +1,207 @@
+package dqn_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/dqn"
+	"github.com/eltorocorp/reinforcement-learning/pkg/env"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/stretchr/testify/assert"
+)
+
+type testAction string
+
+func (a testAction) ID() string { return string(a) }
+
+type testState struct {
+	features []float64
+	actions  []iface.Actioner
+	terminal bool
+}
+
+func (s *testState) PossibleActions() []iface.Actioner      { return s.actions }
+func (s *testState) ActionIsCompatible(iface.Actioner) bool { return true }
+func (s *testState) ID() string                             { return fmt.Sprint(s.features) }
+func (s *testState) Apply(iface.Actioner) error             { return nil }
+func (s *testState) Features() []float64                    { return s.features }
+func (s *testState) IsTerminal() bool                       { return s.terminal }
+func (s *testState) GetAction(id string) (iface.Actioner, error) {
+	return testAction(id), nil
+}
+
+func Test_NetworkTrain(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	n, err := dqn.NewNetwork(rng, 1e-2, 2, 16, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// XOR cannot be learned without a hidden layer.
+	inputs := [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}}
+	targets := []float64{0, 1, 1, 0}
+	outputs := []int{0, 0, 0, 0}
+	for i := 0; i < 2000; i++ {
+		n.Train(inputs, outputs, targets)
+	}
+	for i, x := range inputs {
+		assert.InDelta(t, targets[i], n.Predict(x)[0], .1)
+	}
+}
+
+func Test_NewNetworkErrors(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	_, err := dqn.NewNetwork(rng, 1e-3, 2)
+	assert.EqualError(t, err, "a network requires at least an input and an output size")
+	_, err = dqn.NewNetwork(rng, 1e-3, 2, 0, 1)
+	assert.EqualError(t, err, "layer sizes must be positive")
+}
+
+func Test_ConfigValidation(t *testing.T) {
+	c := dqn.DefaultConfig([]string{"A", "A"}, 2)
+	_, err := dqn.NewAgent(c)
+	assert.EqualError(t, err, "action 'A' is listed more than once")
+
+	c = dqn.DefaultConfig([]string{"A"}, 2)
+	c.ReplayCapacity = 1
+	_, err = dqn.NewAgent(c)
+	assert.EqualError(t, err, "replay capacity must be at least the batch size")
+
+	c = dqn.DefaultConfig([]string{"A"}, 2)
+	c.LearningRate = 0
+	_, err = dqn.NewAgent(c)
+	assert.EqualError(t, err, "learning rate must be positive and finite, got 0")
+
+	c = dqn.DefaultConfig([]string{"A"}, 2)
+	c.DiscountFactor = 1.5
+	_, err = dqn.NewAgent(c)
+	assert.EqualError(t, err, "discount factor must be between 0 and 1, got 1.5")
+
+	c = dqn.DefaultConfig([]string{"A"}, 2)
+	c.Exploration = qlearning.ConstantRate(2)
+	_, err = dqn.NewAgent(c)
+	assert.EqualError(t, err, "initial exploration rate must be between 0 and 1, got 2")
+}
+
+// In the two-step chain below, "right" from the start state yields nothing
+// immediately but leads to a state where "right" yields a large reward. The
+// agent must bootstrap through the target network to prefer "right" at the
+// start.
+func Test_AgentLearnsChain(t *testing.T) {
+	actions := []iface.Actioner{testAction("left"), testAction("right")}
+	start := &testState{features: []float64{1, 0}, actions: actions}
+	middle := &testState{features: []float64{0, 1}, actions: actions}
+	end := &testState{features: []float64{0, 0}, actions: actions, terminal: true}
+
+	c := dqn.DefaultConfig([]string{"left", "right"}, 2)
+	c.Hidden = []int{16}
+	c.LearningRate = 1e-2
+	c.DiscountFactor = .9
+	c.WarmUp = 32
+	c.TargetSync = 20
+	c.Seed = 7
+	a, err := dqn.NewAgent(c)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 0; i < 500; i++ {
+		a.Learn(start, testAction("left"), end, .5)
+		a.Learn(start, testAction("right"), middle, 0)
+		a.Learn(middle, testAction("left"), end, 0)
+		a.Learn(middle, testAction("right"), end, 1)
+	}
+
+	a.SetExploration(qlearning.ConstantRate(0))
+	action, err := a.RecommendAction(start)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "right", action.ID())
+	}
+	q, err := a.QValues(start)
+	if assert.NoError(t, err) {
+		assert.InDelta(t, .9, q["right"], .1)
+		assert.InDelta(t, .5, q["left"], .1)
+	}
+}
+
+func Test_AgentRecommendActionErrors(t *testing.T) {
+	a, err := dqn.NewAgent(dqn.DefaultConfig([]string{"A"}, 1))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = a.RecommendAction(&testState{features: []float64{1}})
+	assert.EqualError(t, err, "state '[1]' reports no possible actions")
+
+	_, err = a.RecommendAction(&testState{features: []float64{1}, actions: []iface.Actioner{testAction("B")}})
+	assert.EqualError(t, err, "action 'B' is not known to the agent")
+
+	_, err = a.RecommendAction(&testState{features: []float64{1, 2}})
+	assert.EqualError(t, err, "state '[1 2]' provided 2 features, expected 1")
+}
+
+func Test_SaveLoadWeights(t *testing.T) {
+	c := dqn.DefaultConfig([]string{"A", "B"}, 2)
+	c.Seed = 1
+	original, err := dqn.NewAgent(c)
+	if !assert.NoError(t, err) {
+		return
+	}
+	buf := &bytes.Buffer{}
+	if !assert.NoError(t, original.SaveWeights(buf)) {
+		return
+	}
+
+	c.Seed = 2
+	restored, err := dqn.NewAgent(c)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.NoError(t, restored.LoadWeights(bytes.NewReader(buf.Bytes()))) {
+		return
+	}
+	state := &testState{features: []float64{.3, -.2}}
+	exp, _ := original.QValues(state)
+	act, _ := restored.QValues(state)
+	assert.Equal(t, exp, act)
+
+	other, _ := dqn.NewAgent(dqn.DefaultConfig([]string{"A", "C"}, 2))
+	err = other.LoadWeights(bytes.NewReader(buf.Bytes()))
+	assert.EqualError(t, err, "weights describe action 'B' at output 1, expected 'C'")
+}
+
+// A random policy balances the pole for about 20 steps on average. After a
+// few hundred episodes of training, the greedy policy should do much better.
+func Test_AgentLearnsCartPole(t *testing.T) {
+	cartPole := env.NewCartPole(1)
+	task := env.NewTask(cartPole, nil)
+
+	c := dqn.DefaultConfig(cartPole.Actions(), 4)
+	c.Exploration = qlearning.ExponentialDecay(1, .995, .01)
+	c.Seed = 1
+	a, err := dqn.NewAgent(c)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 0; i < 200; i++ {
+		if _, _, err := task.RunEpisode(a, true); !assert.NoError(t, err) {
+			return
+		}
+	}
+
+	a.SetExploration(qlearning.ConstantRate(0))
+	total := 0
+	for i := 0; i < 10; i++ {
+		_, steps, err := task.RunEpisode(a, false)
+		if !assert.NoError(t, err) {
+			return
+		}
+		total += steps
+	}
+	average := float64(total) / 10
+	assert.True(t, average >= 100, "average episode length was %v", average)
+}
@@ -0,0 +1,269 @@
+package dqn
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Network is a minimal multilayer perceptron consisting of fully connected
+// layers. Every hidden layer uses a ReLU activation, and the output layer is
+// linear. Parameters are optimized via Adam.
+// see https://arxiv.org/abs/1412.6980
+type Network struct {
+	layers       []*dense
+	learningRate float64
+	steps        int
+}
+
+type dense struct {
+	inputs  int
+	outputs int
+	weights []float64
+	biases  []float64
+
+	gradWeights []float64
+	gradBiases  []float64
+
+	// first and second moment estimates for Adam.
+	mWeights []float64
+	vWeights []float64
+	mBiases  []float64
+	vBiases  []float64
+}
+
+const (
+	adamBeta1   = 0.9
+	adamBeta2   = 0.999
+	adamEpsilon = 1e-8
+)
+
+// NewNetwork returns a reference to a new Network with the specified layer
+// sizes, the first of which is the number of inputs, and the last of which is
+// the number of outputs. Weights are initialized using rng.
+func NewNetwork(rng *rand.Rand, learningRate float64, sizes ...int) (*Network, error) {
+	if len(sizes) < 2 {
+		return nil, fmt.Errorf("a network requires at least an input and an output size")
+	}
+	n := &Network{learningRate: learningRate}
+	for i := 1; i < len(sizes); i++ {
+		if sizes[i-1] < 1 || sizes[i] < 1 {
+			return nil, fmt.Errorf("layer sizes must be positive")
+		}
+		l := newDense(sizes[i-1], sizes[i])
+		// He initialization, which suits ReLU activations.
+		scale := math.Sqrt(2 / float64(l.inputs))
+		for j := range l.weights {
+			l.weights[j] = rng.NormFloat64() * scale
+		}
+		n.layers = append(n.layers, l)
+	}
+	return n, nil
+}
+
+func newDense(inputs, outputs int) *dense {
+	return &dense{
+		inputs:      inputs,
+		outputs:     outputs,
+		weights:     make([]float64, inputs*outputs),
+		biases:      make([]float64, outputs),
+		gradWeights: make([]float64, inputs*outputs),
+		gradBiases:  make([]float64, outputs),
+		mWeights:    make([]float64, inputs*outputs),
+		vWeights:    make([]float64, inputs*outputs),
+		mBiases:     make([]float64, outputs),
+		vBiases:     make([]float64, outputs),
+	}
+}
+
+// Inputs returns the number of inputs that the network expects.
+func (n *Network) Inputs() int {
+	return n.layers[0].inputs
+}
+
+// Outputs returns the number of outputs that the network produces.
+func (n *Network) Outputs() int {
+	return n.layers[len(n.layers)-1].outputs
+}
+
+// Predict returns the network's outputs for the supplied inputs.
+func (n *Network) Predict(x []float64) []float64 {
+	activations := n.forward(x)
+	return activations[len(activations)-1]
+}
+
+// forward returns the activations of every layer, beginning with the inputs.
+func (n *Network) forward(x []float64) [][]float64 {
+	activations := make([][]float64, len(n.layers)+1)
+	activations[0] = x
+	for i, l := range n.layers {
+		in := activations[i]
+		out := make([]float64, l.outputs)
+		for o := 0; o < l.outputs; o++ {
+			sum := l.biases[o]
+			row := l.weights[o*l.inputs : (o+1)*l.inputs]
+			for j, v := range in {
+				sum += row[j] * v
+			}
+			if i < len(n.layers)-1 && sum < 0 {
+				sum = 0
+			}
+			out[o] = sum
+		}
+		activations[i+1] = out
+	}
+	return activations
+}
+
+// backward accumulates the gradient of the loss with respect to every
+// parameter, given the gradient of the loss with respect to the outputs.
+func (n *Network) backward(activations [][]float64, gradOutputs []float64) {
+	grad := gradOutputs
+	for i := len(n.layers) - 1; i >= 0; i-- {
+		l := n.layers[i]
+		in := activations[i]
+		if i < len(n.layers)-1 {
+			for o, a := range activations[i+1] {
+				if a <= 0 {
+					grad[o] = 0
+				}
+			}
+		}
+		gradIn := make([]float64, l.inputs)
+		for o, g := range grad {
+			if g == 0 {
+				continue
+			}
+			l.gradBiases[o] += g
+			row := l.weights[o*l.inputs : (o+1)*l.inputs]
+			gradRow := l.gradWeights[o*l.inputs : (o+1)*l.inputs]
+			for j, v := range in {
+				gradRow[j] += g * v
+				gradIn[j] += g * row[j]
+			}
+		}
+		grad = gradIn
+	}
+}
+
+// Train performs a single optimization step that moves the output at index
+// outputs[i] for inputs[i] toward targets[i]. Outputs that are not targeted
+// are unaffected by the loss. The Huber loss is used, so that large errors do
+// not produce large gradients. The mean loss of the batch prior to the update
+// is returned.
+func (n *Network) Train(inputs [][]float64, outputs []int, targets []float64) float64 {
+	if len(inputs) == 0 {
+		return 0
+	}
+	for _, l := range n.layers {
+		zero(l.gradWeights)
+		zero(l.gradBiases)
+	}
+
+	loss := 0.0
+	batchSize := float64(len(inputs))
+	for i, x := range inputs {
+		activations := n.forward(x)
+		predicted := activations[len(activations)-1]
+		diff := predicted[outputs[i]] - targets[i]
+		gradOutputs := make([]float64, len(predicted))
+		if math.Abs(diff) <= 1 {
+			loss += diff * diff / 2
+			gradOutputs[outputs[i]] = diff / batchSize
+		} else {
+			loss += math.Abs(diff) - .5
+			gradOutputs[outputs[i]] = math.Copysign(1, diff) / batchSize
+		}
+		n.backward(activations, gradOutputs)
+	}
+
+	n.steps++
+	correction1 := 1 - math.Pow(adamBeta1, float64(n.steps))
+	correction2 := 1 - math.Pow(adamBeta2, float64(n.steps))
+	for _, l := range n.layers {
+		n.adam(l.weights, l.gradWeights, l.mWeights, l.vWeights, correction1, correction2)
+		n.adam(l.biases, l.gradBiases, l.mBiases, l.vBiases, correction1, correction2)
+	}
+	return loss / batchSize
+}
+
+func (n *Network) adam(params, grads, m, v []float64, correction1, correction2 float64) {
+	for i, g := range grads {
+		m[i] = adamBeta1*m[i] + (1-adamBeta1)*g
+		v[i] = adamBeta2*v[i] + (1-adamBeta2)*g*g
+		params[i] -= n.learningRate * (m[i] / correction1) / (math.Sqrt(v[i]/correction2) + adamEpsilon)
+	}
+}
+
+// CopyFrom overwrites the network's parameters with those of another network
+// of identical shape. The optimizer state is not copied.
+func (n *Network) CopyFrom(other *Network) {
+	for i, l := range n.layers {
+		copy(l.weights, other.layers[i].weights)
+		copy(l.biases, other.layers[i].biases)
+	}
+}
+
+// Clone returns a copy of the network's parameters with fresh optimizer
+// state.
+func (n *Network) Clone() *Network {
+	c := &Network{learningRate: n.learningRate}
+	for _, l := range n.layers {
+		c.layers = append(c.layers, newDense(l.inputs, l.outputs))
+	}
+	c.CopyFrom(n)
+	return c
+}
+
+// LayerContext describes the parameters of a single layer of a Network.
+// Weights are stored in row-major order, with one row per output.
+type LayerContext struct {
+	Inputs  int
+	Outputs int
+	Weights []float64
+	Biases  []float64
+}
+
+// GetLayers returns the parameters of every layer of the network.
+func (n *Network) GetLayers() []LayerContext {
+	layers := make([]LayerContext, len(n.layers))
+	for i, l := range n.layers {
+		layers[i] = LayerContext{
+			Inputs:  l.inputs,
+			Outputs: l.outputs,
+			Weights: append([]float64(nil), l.weights...),
+			Biases:  append([]float64(nil), l.biases...),
+		}
+	}
+	return layers
+}
+
+// SetLayers replaces the parameters of the network. The optimizer state is
+// reset.
+func (n *Network) SetLayers(layers []LayerContext) error {
+	if len(layers) == 0 {
+		return fmt.Errorf("a network requires at least one layer")
+	}
+	built := make([]*dense, len(layers))
+	for i, lc := range layers {
+		if i > 0 && lc.Inputs != layers[i-1].Outputs {
+			return fmt.Errorf("layer %v expects %v inputs, but the previous layer has %v outputs", i, lc.Inputs, layers[i-1].Outputs)
+		}
+		if len(lc.Weights) != lc.Inputs*lc.Outputs || len(lc.Biases) != lc.Outputs {
+			return fmt.Errorf("layer %v parameters do not match its %vx%v shape", i, lc.Inputs, lc.Outputs)
+		}
+		l := newDense(lc.Inputs, lc.Outputs)
+		copy(l.weights, lc.Weights)
+		copy(l.biases, lc.Biases)
+		built[i] = l
+	}
+	n.layers = built
+	n.steps = 0
+	return nil
+}
+
+func zero(s []float64) {
+	for i := range s {
+		s[i] = 0
+	}
+}
@@ -0,0 +1,51 @@
+package dqn
+
+import "math/rand"
+
+// transition is a single observed step of experience.
+type transition struct {
+	state     []float64
+	action    int
+	reward    float64
+	nextState []float64
+	// nextMask indicates which outputs are valid actions in the next state.
+	nextMask []bool
+	terminal bool
+}
+
+// replayBuffer is a fixed-capacity ring buffer of transitions from which
+// minibatches are sampled uniformly at random.
+type replayBuffer struct {
+	transitions []transition
+	capacity    int
+	next        int
+}
+
+func newReplayBuffer(capacity int) *replayBuffer {
+	return &replayBuffer{
+		transitions: make([]transition, 0, capacity),
+		capacity:    capacity,
+	}
+}
+
+func (b *replayBuffer) add(t transition) {
+	if len(b.transitions) < b.capacity {
+		b.transitions = append(b.transitions, t)
+	} else {
+		b.transitions[b.next] = t
+	}
+	b.next = (b.next + 1) % b.capacity
+}
+
+func (b *replayBuffer) len() int {
+	return len(b.transitions)
+}
+
+// sample returns n transitions chosen uniformly at random, with replacement.
+func (b *replayBuffer) sample(rng *rand.Rand, n int) []transition {
+	batch := make([]transition, n)
+	for i := range batch {
+		batch[i] = b.transitions[rng.Intn(len(b.transitions))]
+	}
+	return batch
+}
@@ -0,0 +1,132 @@
+package drift
+
+import (
+	"fmt"
+	"math"
+)
+
+// minSubWindow is the fewest values either side of a split that ADWIN will
+// compare.
+const minSubWindow = 5
+
+// ADWIN detects changes with the ADaptive WINdowing algorithm. It maintains a
+// window of recent values, and whenever two sub-windows (older and newer)
+// have means that differ by more than chance would allow, it drops the older
+// values. The window thus grows while the stream is stationary and shrinks
+// when it changes, and the width of the window indicates how long the stream
+// has been stable.
+//
+// This implementation retains the values of the window explicitly (ADWIN0)
+// up to a maximum width, rather than compressing them into an exponential
+// histogram, so each value costs time in proportion to the width.
+// see https://doi.org/10.1137/1.9781611972771.42
+type ADWIN struct {
+	delta    float64
+	maxWidth int
+	window   []float64
+}
+
+// NewADWIN returns a reference to a new ADWIN detector.
+//
+// delta:
+//
+//	The greatest tolerated probability of a false alarm. Smaller values
+//	produce fewer false alarms, but detect changes later. Must be between 0
+//	and 1 exclusive. 0.002 is typical.
+//
+// maxWidth:
+//
+//	The greatest number of values retained. Must be at least 2*5, so that two
+//	sub-windows of 5 values can be compared.
+func NewADWIN(delta float64, maxWidth int) (*ADWIN, error) {
+	if !(delta > 0 && delta < 1) {
+		return nil, fmt.Errorf("delta must be between 0 and 1 exclusive, got %v", delta)
+	}
+	if maxWidth < 2*minSubWindow {
+		return nil, fmt.Errorf("maximum width must be at least %v, got %v", 2*minSubWindow, maxWidth)
+	}
+	return &ADWIN{delta: delta, maxWidth: maxWidth}, nil
+}
+
+// Add includes value in the window, and reports any change detected as a
+// result. When a change is detected, the older values are dropped, so a
+// change is reported only once.
+func (a *ADWIN) Add(value float64) Change {
+	a.window = append(a.window, value)
+	if len(a.window) > a.maxWidth {
+		a.window = a.window[1:]
+	}
+
+	change := NoChange
+	for {
+		detected := a.detect()
+		if detected == NoChange {
+			return change
+		}
+		change = detected
+		a.window = a.window[1:]
+	}
+}
+
+// detect returns the change between the older and newer portions of the
+// window at any split where their means differ significantly.
+func (a *ADWIN) detect() Change {
+	n := len(a.window)
+	if n < 2*minSubWindow {
+		return NoChange
+	}
+
+	total, totalSquares := 0.0, 0.0
+	for _, v := range a.window {
+		total += v
+		totalSquares += v * v
+	}
+	mean := total / float64(n)
+	variance := math.Max(totalSquares/float64(n)-mean*mean, 0)
+	logTerm := math.Log(2 * float64(n) / a.delta)
+
+	older := 0.0
+	for i := 1; i < n; i++ {
+		older += a.window[i-1]
+		if i < minSubWindow || n-i < minSubWindow {
+			continue
+		}
+		n0, n1 := float64(i), float64(n-i)
+		mean0 := older / n0
+		mean1 := (total - older) / n1
+		m := 1 / (1/n0 + 1/n1)
+		epsilon := math.Sqrt(2/m*variance*logTerm) + 2/(3*m)*logTerm
+		if math.Abs(mean1-mean0) > epsilon {
+			if mean1 > mean0 {
+				return Increase
+			}
+			return Decrease
+		}
+	}
+	return NoChange
+}
+
+// Width returns the number of values in the window.
+func (a *ADWIN) Width() int {
+	return len(a.window)
+}
+
+// Mean returns the mean of the values in the window, or 0 if the window is
+// empty.
+func (a *ADWIN) Mean() float64 {
+	if len(a.window) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range a.window {
+		total += v
+	}
+	return total / float64(len(a.window))
+}
+
+// Reset discards everything the detector has observed.
+func (a *ADWIN) Reset() {
+	a.window = nil
+}
+
+var _ Detector = (*ADWIN)(nil)
@@ -0,0 +1,4 @@
+// Package drift provides detectors that monitor a stream of values, such as
+// the rewards observed for an action, and report when the distribution of the
+// stream changes significantly.
+package drift
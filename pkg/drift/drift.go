@@ -0,0 +1,33 @@
+package drift
+
+// Change describes a change detected in a stream of values.
+type Change int
+
+const (
+	// NoChange indicates that no change was detected.
+	NoChange Change = iota
+	// Increase indicates that the mean of the stream has increased.
+	Increase
+	// Decrease indicates that the mean of the stream has decreased.
+	Decrease
+)
+
+func (c Change) String() string {
+	switch c {
+	case Increase:
+		return "increase"
+	case Decrease:
+		return "decrease"
+	default:
+		return "none"
+	}
+}
+
+// Detector monitors a stream of values for changes in their distribution.
+type Detector interface {
+	// Add includes value in the stream, and reports any change detected as a
+	// result.
+	Add(value float64) Change
+	// Reset discards everything the detector has observed.
+	Reset()
+}
@@ -0,0 +1,89 @@
+package drift_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/drift"
+	"github.com/stretchr/testify/assert"
+)
+
+// stream returns n values with the specified mean and a little noise.
+func stream(rng *rand.Rand, n int, mean float64) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = mean + rng.NormFloat64()*.1
+	}
+	return values
+}
+
+// firstChange returns the index of the first value at which d reports a
+// change, along with the change, or -1 if no change is reported.
+func firstChange(d drift.Detector, values []float64) (int, drift.Change) {
+	for i, v := range values {
+		if change := d.Add(v); change != drift.NoChange {
+			return i, change
+		}
+	}
+	return -1, drift.NoChange
+}
+
+func Test_PageHinkley(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ph, err := drift.NewPageHinkley(.05, 5, 30)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	i, _ := firstChange(ph, stream(rng, 500, 1))
+	assert.Equal(t, -1, i, "no change in a stationary stream")
+
+	i, change := firstChange(ph, stream(rng, 100, .2))
+	assert.Equal(t, drift.Decrease, change)
+	assert.True(t, i >= 0 && i < 20, "detected after %v values", i)
+
+	ph.Reset()
+	firstChange(ph, stream(rng, 100, 0))
+	_, change = firstChange(ph, stream(rng, 100, 1))
+	assert.Equal(t, drift.Increase, change)
+}
+
+func Test_ADWIN(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	adwin, err := drift.NewADWIN(.002, 1000)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	i, _ := firstChange(adwin, stream(rng, 500, 1))
+	assert.Equal(t, -1, i, "no change in a stationary stream")
+	assert.Equal(t, 500, adwin.Width())
+
+	i, change := firstChange(adwin, stream(rng, 100, .2))
+	assert.Equal(t, drift.Decrease, change)
+	assert.True(t, i >= 0 && i < 20, "detected after %v values", i)
+	assert.True(t, adwin.Width() < 500, "older values are dropped")
+
+	adwin.Reset()
+	assert.Equal(t, 0, adwin.Width())
+	assert.Equal(t, 0.0, adwin.Mean())
+}
+
+func Test_NewDetectorsInvalid(t *testing.T) {
+	_, err := drift.NewPageHinkley(-1, 1, 0)
+	assert.Error(t, err)
+	_, err = drift.NewPageHinkley(0, 0, 0)
+	assert.Error(t, err)
+	_, err = drift.NewPageHinkley(0, 1, -1)
+	assert.Error(t, err)
+	_, err = drift.NewADWIN(0, 100)
+	assert.Error(t, err)
+	_, err = drift.NewADWIN(.01, 5)
+	assert.Error(t, err)
+}
+
+func Test_ChangeString(t *testing.T) {
+	assert.Equal(t, "none", drift.NoChange.String())
+	assert.Equal(t, "increase", drift.Increase.String())
+	assert.Equal(t, "decrease", drift.Decrease.String())
+}
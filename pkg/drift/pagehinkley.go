@@ -0,0 +1,83 @@
+package drift
+
+import (
+	"fmt"
+	"math"
+)
+
+// PageHinkley detects abrupt changes in the mean of a stream with the
+// Page-Hinkley test. It accumulates the deviation of each value from the
+// running mean, and reports a change when the accumulated deviation moves
+// further than a threshold from its extreme. It is cheap, and well suited to
+// detecting a sudden step in the mean.
+// see https://doi.org/10.1093/biomet/41.1-2.100
+type PageHinkley struct {
+	delta      float64
+	threshold  float64
+	minSamples int
+
+	count   int
+	mean    float64
+	up      float64
+	upMin   float64
+	down    float64
+	downMax float64
+}
+
+// NewPageHinkley returns a reference to a new PageHinkley detector.
+//
+// delta:
+//
+//	The magnitude of change in the mean that is tolerated without being
+//	accumulated. Must not be negative.
+//
+// threshold:
+//
+//	The accumulated deviation required to report a change. Larger thresholds
+//	produce fewer false alarms, but detect changes later. Must be positive.
+//
+// minSamples:
+//
+//	The number of values that must be observed before any change is reported.
+func NewPageHinkley(delta, threshold float64, minSamples int) (*PageHinkley, error) {
+	if !(delta >= 0) || math.IsInf(delta, 1) {
+		return nil, fmt.Errorf("delta must not be negative, got %v", delta)
+	}
+	if !(threshold > 0) || math.IsInf(threshold, 1) {
+		return nil, fmt.Errorf("threshold must be positive, got %v", threshold)
+	}
+	if minSamples < 0 {
+		return nil, fmt.Errorf("minimum samples must not be negative, got %v", minSamples)
+	}
+	return &PageHinkley{delta: delta, threshold: threshold, minSamples: minSamples}, nil
+}
+
+// Add includes value in the stream, and reports any change detected as a
+// result. Once a change is reported, the detector continues to report it
+// until it is Reset.
+func (ph *PageHinkley) Add(value float64) Change {
+	ph.count++
+	ph.mean += (value - ph.mean) / float64(ph.count)
+	ph.up += value - ph.mean - ph.delta
+	ph.upMin = math.Min(ph.upMin, ph.up)
+	ph.down += value - ph.mean + ph.delta
+	ph.downMax = math.Max(ph.downMax, ph.down)
+
+	if ph.count < ph.minSamples {
+		return NoChange
+	}
+	if ph.up-ph.upMin > ph.threshold {
+		return Increase
+	}
+	if ph.downMax-ph.down > ph.threshold {
+		return Decrease
+	}
+	return NoChange
+}
+
+// Reset discards everything the detector has observed.
+func (ph *PageHinkley) Reset() {
+	*ph = PageHinkley{delta: ph.delta, threshold: ph.threshold, minSamples: ph.minSamples}
+}
+
+var _ Detector = (*PageHinkley)(nil)
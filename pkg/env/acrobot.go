@@ -0,0 +1,140 @@
+package env
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Acrobot is the classic problem of swinging up a two-link pendulum that is
+// actuated only at the joint between the links. A reward of -1 is earned for
+// every step until the tip of the lower link swings above a height of one
+// link length over the pivot, which terminates the episode. The episode is
+// truncated if MaxSteps is reached first.
+//
+// Observations are [cos θ1, sin θ1, cos θ2, sin θ2, θ1 velocity, θ2 velocity].
+//
+// see https://github.com/openai/gym/blob/master/gym/envs/classic_control/acrobot.py
+type Acrobot struct {
+	MaxSteps int
+	rng      *rand.Rand
+	state    [4]float64
+	steps    int
+}
+
+const (
+	acrobotLinkLength   = 1.0
+	acrobotLinkMass     = 1.0
+	acrobotLinkCOM      = 0.5
+	acrobotLinkMOI      = 1.0
+	acrobotGravity      = 9.8
+	acrobotDT           = 0.2
+	acrobotMaxVelocity1 = 4 * math.Pi
+	acrobotMaxVelocity2 = 9 * math.Pi
+)
+
+// NewAcrobot returns a reference to a new Acrobot seeded with seed.
+func NewAcrobot(seed int64) *Acrobot {
+	a := &Acrobot{
+		MaxSteps: 500,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+	a.Reset()
+	return a
+}
+
+// Actions lists the torques that can be applied to the joint.
+func (a *Acrobot) Actions() []string {
+	return []string{"negative", "none", "positive"}
+}
+
+// Bounds returns the bounds of each dimension of an observation.
+func (a *Acrobot) Bounds() (low, high []float64) {
+	return []float64{-1, -1, -1, -1, -acrobotMaxVelocity1, -acrobotMaxVelocity2},
+		[]float64{1, 1, 1, 1, acrobotMaxVelocity1, acrobotMaxVelocity2}
+}
+
+// Reset returns the pendulum to a random position near hanging at rest.
+func (a *Acrobot) Reset() []float64 {
+	for i := range a.state {
+		a.state[i] = a.rng.Float64()*.2 - .1
+	}
+	a.steps = 0
+	return a.observation()
+}
+
+// Step applies a torque of -1 (0), 0 (1), or 1 (2) to the joint, and
+// integrates the dynamics over a single time step using the Runge-Kutta
+// method.
+func (a *Acrobot) Step(action int) ([]float64, float64, bool, bool) {
+	torque := float64(action - 1)
+	s := a.state
+
+	k1 := acrobotDerivatives(s, torque)
+	k2 := acrobotDerivatives(addScaled(s, k1, acrobotDT/2), torque)
+	k3 := acrobotDerivatives(addScaled(s, k2, acrobotDT/2), torque)
+	k4 := acrobotDerivatives(addScaled(s, k3, acrobotDT), torque)
+	for i := range s {
+		s[i] += acrobotDT / 6 * (k1[i] + 2*k2[i] + 2*k3[i] + k4[i])
+	}
+
+	s[0] = wrapAngle(s[0])
+	s[1] = wrapAngle(s[1])
+	s[2] = math.Max(math.Min(s[2], acrobotMaxVelocity1), -acrobotMaxVelocity1)
+	s[3] = math.Max(math.Min(s[3], acrobotMaxVelocity2), -acrobotMaxVelocity2)
+	a.state = s
+	a.steps++
+
+	if -math.Cos(s[0])-math.Cos(s[1]+s[0]) > 1 {
+		return a.observation(), 0, true, false
+	}
+	return a.observation(), -1, false, a.steps >= a.MaxSteps
+}
+
+func (a *Acrobot) observation() []float64 {
+	return []float64{
+		math.Cos(a.state[0]),
+		math.Sin(a.state[0]),
+		math.Cos(a.state[1]),
+		math.Sin(a.state[1]),
+		a.state[2],
+		a.state[3],
+	}
+}
+
+// acrobotDerivatives returns the time derivative of the state, following the
+// dynamics described in Sutton and Barto's book.
+func acrobotDerivatives(s [4]float64, torque float64) [4]float64 {
+	const (
+		m1, m2   = acrobotLinkMass, acrobotLinkMass
+		l1       = acrobotLinkLength
+		lc1, lc2 = acrobotLinkCOM, acrobotLinkCOM
+		i1, i2   = acrobotLinkMOI, acrobotLinkMOI
+		g        = acrobotGravity
+	)
+	theta1, theta2, dTheta1, dTheta2 := s[0], s[1], s[2], s[3]
+
+	d1 := m1*lc1*lc1 + m2*(l1*l1+lc2*lc2+2*l1*lc2*math.Cos(theta2)) + i1 + i2
+	d2 := m2*(lc2*lc2+l1*lc2*math.Cos(theta2)) + i2
+	phi2 := m2 * lc2 * g * math.Cos(theta1+theta2-math.Pi/2)
+	phi1 := -m2*l1*lc2*dTheta2*dTheta2*math.Sin(theta2) -
+		2*m2*l1*lc2*dTheta2*dTheta1*math.Sin(theta2) +
+		(m1*lc1+m2*l1)*g*math.Cos(theta1-math.Pi/2) + phi2
+	ddTheta2 := (torque + d2/d1*phi1 - m2*l1*lc2*dTheta1*dTheta1*math.Sin(theta2) - phi2) /
+		(m2*lc2*lc2 + i2 - d2*d2/d1)
+	ddTheta1 := -(d2*ddTheta2 + phi1) / d1
+	return [4]float64{dTheta1, dTheta2, ddTheta1, ddTheta2}
+}
+
+func addScaled(s, d [4]float64, scale float64) [4]float64 {
+	for i := range s {
+		s[i] += d[i] * scale
+	}
+	return s
+}
+
+// wrapAngle wraps an angle into the range [-π, π).
+func wrapAngle(theta float64) float64 {
+	return theta - 2*math.Pi*math.Floor((theta+math.Pi)/(2*math.Pi))
+}
+
+var _ Environment = (*Acrobot)(nil)
@@ -0,0 +1,98 @@
+package env
+
+import (
+	"math"
+	"math/rand"
+)
+
+// CartPole is the classic problem of balancing a pole on a cart that moves
+// along a frictionless track. A reward of 1 is earned for every step that the
+// pole remains upright. The episode terminates when the pole falls more than
+// 12 degrees from vertical or the cart leaves the track, and is truncated if
+// MaxSteps is reached first.
+//
+// Observations are [cart position, cart velocity, pole angle, pole angular
+// velocity].
+//
+// see https://github.com/openai/gym/blob/master/gym/envs/classic_control/cartpole.py
+type CartPole struct {
+	MaxSteps int
+	rng      *rand.Rand
+	state    [4]float64
+	steps    int
+}
+
+const (
+	cartPoleGravity        = 9.8
+	cartPoleCartMass       = 1.0
+	cartPolePoleMass       = 0.1
+	cartPoleTotalMass      = cartPoleCartMass + cartPolePoleMass
+	cartPoleHalfLength     = 0.5
+	cartPolePoleMomentArm  = cartPolePoleMass * cartPoleHalfLength
+	cartPoleForce          = 10.0
+	cartPoleTau            = 0.02
+	cartPoleAngleThreshold = 12 * 2 * math.Pi / 360
+	cartPoleXThreshold     = 2.4
+)
+
+// NewCartPole returns a reference to a new CartPole seeded with seed.
+func NewCartPole(seed int64) *CartPole {
+	c := &CartPole{
+		MaxSteps: 500,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+	c.Reset()
+	return c
+}
+
+// Actions lists the actions that can be applied to the cart.
+func (c *CartPole) Actions() []string {
+	return []string{"left", "right"}
+}
+
+// Bounds returns the practical bounds of each dimension of an observation.
+func (c *CartPole) Bounds() (low, high []float64) {
+	return []float64{-cartPoleXThreshold, -3, -cartPoleAngleThreshold, -3.5},
+		[]float64{cartPoleXThreshold, 3, cartPoleAngleThreshold, 3.5}
+}
+
+// Reset returns the cart and pole to a random position near equilibrium.
+func (c *CartPole) Reset() []float64 {
+	for i := range c.state {
+		c.state[i] = c.rng.Float64()*.1 - .05
+	}
+	c.steps = 0
+	return c.observation()
+}
+
+// Step pushes the cart left (0) or right (1).
+func (c *CartPole) Step(action int) ([]float64, float64, bool, bool) {
+	x, xDot, theta, thetaDot := c.state[0], c.state[1], c.state[2], c.state[3]
+	force := cartPoleForce
+	if action == 0 {
+		force = -cartPoleForce
+	}
+	cosTheta, sinTheta := math.Cos(theta), math.Sin(theta)
+
+	temp := (force + cartPolePoleMomentArm*thetaDot*thetaDot*sinTheta) / cartPoleTotalMass
+	thetaAcc := (cartPoleGravity*sinTheta - cosTheta*temp) /
+		(cartPoleHalfLength * (4.0/3.0 - cartPolePoleMass*cosTheta*cosTheta/cartPoleTotalMass))
+	xAcc := temp - cartPolePoleMomentArm*thetaAcc*cosTheta/cartPoleTotalMass
+
+	x += cartPoleTau * xDot
+	xDot += cartPoleTau * xAcc
+	theta += cartPoleTau * thetaDot
+	thetaDot += cartPoleTau * thetaAcc
+	c.state = [4]float64{x, xDot, theta, thetaDot}
+	c.steps++
+
+	terminated := math.Abs(x) > cartPoleXThreshold ||
+		math.Abs(theta) > cartPoleAngleThreshold
+	return c.observation(), 1, terminated, !terminated && c.steps >= c.MaxSteps
+}
+
+func (c *CartPole) observation() []float64 {
+	return append([]float64(nil), c.state[:]...)
+}
+
+var _ Environment = (*CartPole)(nil)
@@ -0,0 +1,77 @@
+package env
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Discretizer maps continuous observations onto a grid of bins, so that
+// tabular agents can treat each cell of the grid as a distinct state.
+type Discretizer struct {
+	low  []float64
+	high []float64
+	bins []int
+}
+
+// NewDiscretizer returns a reference to a new Discretizer that divides each
+// dimension of an observation, between low and high, into the specified
+// number of equally sized bins. Values outside of the bounds are placed in the
+// nearest bin.
+func NewDiscretizer(low, high []float64, bins []int) (*Discretizer, error) {
+	if len(low) != len(high) || len(low) != len(bins) {
+		return nil, fmt.Errorf("low, high, and bins must have the same length")
+	}
+	for i := range low {
+		if !(high[i] > low[i]) {
+			return nil, fmt.Errorf("bounds for dimension %v are empty: [%v, %v]", i, low[i], high[i])
+		}
+		if bins[i] < 1 {
+			return nil, fmt.Errorf("dimension %v must have at least one bin", i)
+		}
+	}
+	return &Discretizer{
+		low:  append([]float64(nil), low...),
+		high: append([]float64(nil), high...),
+		bins: append([]int(nil), bins...),
+	}, nil
+}
+
+// NewUniformDiscretizer returns a reference to a new Discretizer that divides
+// every dimension of an environment's observations into the same number of
+// bins, using the environment's bounds.
+func NewUniformDiscretizer(e Environment, bins int) (*Discretizer, error) {
+	low, high := e.Bounds()
+	counts := make([]int, len(low))
+	for i := range counts {
+		counts[i] = bins
+	}
+	return NewDiscretizer(low, high, counts)
+}
+
+// Bins returns the bin index of each dimension of an observation.
+func (d *Discretizer) Bins(observation []float64) []int {
+	indices := make([]int, len(d.bins))
+	for i := range d.bins {
+		scaled := (observation[i] - d.low[i]) / (d.high[i] - d.low[i])
+		bin := int(math.Floor(scaled * float64(d.bins[i])))
+		if bin < 0 {
+			bin = 0
+		} else if bin >= d.bins[i] {
+			bin = d.bins[i] - 1
+		}
+		indices[i] = bin
+	}
+	return indices
+}
+
+// ID returns a string that uniquely identifies the bin into which an
+// observation falls.
+func (d *Discretizer) ID(observation []float64) string {
+	parts := make([]string, len(d.bins))
+	for i, bin := range d.Bins(observation) {
+		parts[i] = strconv.Itoa(bin)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,7 @@
+// Package env provides pure-Go implementations of classic continuous control
+// environments (CartPole, MountainCar, and Acrobot), along with a wrapper that
+// exposes them as iface.Stater values so that they can be used with the agents
+// in this module.
+//
+// Every environment is seeded explicitly, so that runs are reproducible.
+package env
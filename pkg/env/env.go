@@ -0,0 +1,26 @@
+package env
+
+// Environment is a simulated system that evolves in discrete time steps in
+// response to the actions applied to it.
+type Environment interface {
+	// Actions lists the ID of every action that can be applied to the
+	// environment. An action is referred to by its index in this list.
+	Actions() []string
+
+	// Bounds returns the practical lower and upper bounds of each dimension
+	// of an observation. Observations may occasionally fall outside of these
+	// bounds.
+	Bounds() (low, high []float64)
+
+	// Reset returns the environment to a random initial condition, and
+	// returns the initial observation.
+	Reset() []float64
+
+	// Step applies an action to the environment, and returns the resulting
+	// observation and the reward for the transition. terminated is true if
+	// the episode ended because the environment reached a terminal state,
+	// which has no future rewards. truncated is true if the episode was
+	// instead cut short by a limit on its length, in which case the state
+	// still has future rewards.
+	Step(action int) (observation []float64, reward float64, terminated, truncated bool)
+}
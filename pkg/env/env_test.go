@@ -0,0 +1,158 @@
+package env_test
+
+import (
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/env"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Seeding(t *testing.T) {
+	testCases := []struct {
+		name string
+		new  func(seed int64) env.Environment
+	}{
+		{"cartpole", func(seed int64) env.Environment { return env.NewCartPole(seed) }},
+		{"mountaincar", func(seed int64) env.Environment { return env.NewMountainCar(seed) }},
+		{"acrobot", func(seed int64) env.Environment { return env.NewAcrobot(seed) }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b, c := tc.new(1), tc.new(1), tc.new(2)
+			initialA, initialB := a.Reset(), b.Reset()
+			assert.Equal(t, initialA, initialB)
+			assert.NotEqual(t, initialA, c.Reset())
+			for i := 0; i < 20; i++ {
+				action := i % len(a.Actions())
+				obsA, rewardA, terminatedA, truncatedA := a.Step(action)
+				obsB, rewardB, terminatedB, truncatedB := b.Step(action)
+				assert.Equal(t, obsA, obsB)
+				assert.Equal(t, rewardA, rewardB)
+				assert.Equal(t, terminatedA, terminatedB)
+				assert.Equal(t, truncatedA, truncatedB)
+			}
+		})
+	}
+}
+
+func Test_CartPoleFalls(t *testing.T) {
+	c := env.NewCartPole(1)
+	steps := 0
+	for terminated := false; !terminated; steps++ {
+		_, _, terminated, _ = c.Step(1)
+	}
+	assert.True(t, steps < 50, "constantly pushing right should topple the pole quickly")
+}
+
+func Test_MountainCarRequiresMomentum(t *testing.T) {
+	m := env.NewMountainCar(1)
+	var obs []float64
+	var terminated, truncated bool
+	for !terminated && !truncated {
+		obs, _, terminated, truncated = m.Step(2)
+	}
+	assert.True(t, obs[0] < .5, "driving straight right should not reach the goal")
+	assert.False(t, terminated, "running out of steps should truncate the episode, not terminate it")
+
+	// Push in the direction of travel to build momentum.
+	m.Reset()
+	reached := false
+	velocity := 0.0
+	for terminated, truncated = false, false; !terminated && !truncated; {
+		action := 2
+		if velocity < 0 {
+			action = 0
+		}
+		obs, _, terminated, truncated = m.Step(action)
+		velocity = obs[1]
+		reached = obs[0] >= .5
+	}
+	assert.True(t, reached)
+}
+
+func Test_AcrobotObservation(t *testing.T) {
+	a := env.NewAcrobot(1)
+	obs := a.Reset()
+	assert.Len(t, obs, 6)
+	for i := 0; i < 100; i++ {
+		obs, reward, terminated, _ := a.Step(2)
+		assert.InDelta(t, 1, obs[0]*obs[0]+obs[1]*obs[1], 1e-9)
+		if !terminated {
+			assert.Equal(t, -1.0, reward)
+		}
+	}
+}
+
+func Test_Discretizer(t *testing.T) {
+	d, err := env.NewDiscretizer([]float64{0, -1}, []float64{1, 1}, []int{4, 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []int{0, 0}, d.Bins([]float64{0, -1}))
+	assert.Equal(t, []int{2, 1}, d.Bins([]float64{.5, 0}))
+	assert.Equal(t, []int{3, 1}, d.Bins([]float64{7, 7}))
+	assert.Equal(t, "0:0", d.ID([]float64{-7, -7}))
+
+	_, err = env.NewDiscretizer([]float64{0}, []float64{1}, []int{0})
+	assert.EqualError(t, err, "dimension 0 must have at least one bin")
+}
+
+func Test_TaskStates(t *testing.T) {
+	d, _ := env.NewUniformDiscretizer(env.NewCartPole(0), 6)
+	task := env.NewTask(env.NewCartPole(3), d)
+	first := task.State()
+	assert.Len(t, first.Features(), 4)
+	assert.Len(t, first.PossibleActions(), 2)
+
+	assert.NoError(t, first.Apply(env.Action("left")))
+	assert.Equal(t, 1.0, task.Reward())
+	assert.NotEqual(t, first, task.State())
+
+	assert.EqualError(t, first.Apply(env.Action("left")), "state '"+first.ID()+"' is not the current state of the task")
+	assert.EqualError(t, task.State().Apply(env.Action("up")), "action 'up' is not possible in state '"+task.State().ID()+"'")
+}
+
+func Test_TabularAgentOnCartPole(t *testing.T) {
+	cp := env.NewCartPole(1)
+	d, _ := env.NewDiscretizer([]float64{-2.4, -3, -.21, -3.5}, []float64{2.4, 3, .21, 3.5}, []int{1, 1, 6, 6})
+	task := env.NewTask(cp, d)
+	agent := qlearning.NewBayesianAgent(1, .5, .99)
+	agent.TieBreaker = func(int) int { return 0 }
+
+	first, _, err := task.RunEpisode(agent, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	best := first
+	for i := 0; i < 200; i++ {
+		reward, steps, err := task.RunEpisode(agent, true)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, float64(steps), reward)
+		if reward > best {
+			best = reward
+		}
+	}
+	assert.True(t, best > first)
+}
+
+func Test_TaskTruncation(t *testing.T) {
+	cp := env.NewCartPole(3)
+	cp.MaxSteps = 1
+	task := env.NewTask(cp, nil)
+	assert.NoError(t, task.State().Apply(env.Action("left")))
+
+	truncated := task.State()
+	assert.True(t, truncated.IsTruncated())
+	assert.False(t, truncated.IsTerminal(), "a truncated state has future rewards")
+	assert.True(t, truncated.Ended())
+	assert.Len(t, truncated.PossibleActions(), 2)
+	assert.False(t, truncated.ActionIsCompatible(env.Action("left")))
+	assert.EqualError(t, truncated.Apply(env.Action("left")), "the episode was truncated at state '"+truncated.ID()+"'")
+
+	_, steps, err := task.RunEpisode(qlearning.NewBayesianAgent(1, .5, .99), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, steps)
+}
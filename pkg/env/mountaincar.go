@@ -0,0 +1,83 @@
+package env
+
+import (
+	"math"
+	"math/rand"
+)
+
+// MountainCar is the classic problem of driving an underpowered car out of a
+// valley. The car cannot climb the hill directly, and must instead build
+// momentum by driving back and forth. A reward of -1 is earned for every step
+// until the car reaches the goal at the top of the right hill, which
+// terminates the episode. The episode is truncated if MaxSteps is reached
+// first.
+//
+// Observations are [position, velocity].
+//
+// see https://github.com/openai/gym/blob/master/gym/envs/classic_control/mountain_car.py
+type MountainCar struct {
+	MaxSteps int
+	rng      *rand.Rand
+	position float64
+	velocity float64
+	steps    int
+}
+
+const (
+	mountainCarMinPosition = -1.2
+	mountainCarMaxPosition = 0.6
+	mountainCarMaxSpeed    = 0.07
+	mountainCarGoal        = 0.5
+	mountainCarForce       = 0.001
+	mountainCarGravity     = 0.0025
+)
+
+// NewMountainCar returns a reference to a new MountainCar seeded with seed.
+func NewMountainCar(seed int64) *MountainCar {
+	m := &MountainCar{
+		MaxSteps: 200,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+	m.Reset()
+	return m
+}
+
+// Actions lists the actions that can be applied to the car.
+func (m *MountainCar) Actions() []string {
+	return []string{"left", "none", "right"}
+}
+
+// Bounds returns the bounds of each dimension of an observation.
+func (m *MountainCar) Bounds() (low, high []float64) {
+	return []float64{mountainCarMinPosition, -mountainCarMaxSpeed},
+		[]float64{mountainCarMaxPosition, mountainCarMaxSpeed}
+}
+
+// Reset places the car at rest near the bottom of the valley.
+func (m *MountainCar) Reset() []float64 {
+	m.position = -.6 + m.rng.Float64()*.2
+	m.velocity = 0
+	m.steps = 0
+	return m.observation()
+}
+
+// Step accelerates the car left (0), not at all (1), or right (2).
+func (m *MountainCar) Step(action int) ([]float64, float64, bool, bool) {
+	m.velocity += float64(action-1)*mountainCarForce - math.Cos(3*m.position)*mountainCarGravity
+	m.velocity = math.Max(math.Min(m.velocity, mountainCarMaxSpeed), -mountainCarMaxSpeed)
+	m.position += m.velocity
+	m.position = math.Max(math.Min(m.position, mountainCarMaxPosition), mountainCarMinPosition)
+	if m.position == mountainCarMinPosition && m.velocity < 0 {
+		m.velocity = 0
+	}
+	m.steps++
+
+	terminated := m.position >= mountainCarGoal
+	return m.observation(), -1, terminated, !terminated && m.steps >= m.MaxSteps
+}
+
+func (m *MountainCar) observation() []float64 {
+	return []float64{m.position, m.velocity}
+}
+
+var _ Environment = (*MountainCar)(nil)
@@ -0,0 +1,209 @@
+package env
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Task adapts an Environment to the iface.Stater interface. Each observation
+// of the environment is exposed as a State whose ID is derived from a
+// Discretizer, and whose features are the raw observation. Thus tabular and
+// function approximation agents can be trained on the same environment.
+type Task struct {
+	env         Environment
+	discretizer *Discretizer
+	actions     []iface.Actioner
+	current     *State
+	reward      float64
+}
+
+// NewTask returns a reference to a new Task. If discretizer is nil, each
+// State's ID is the exact observation, which is rarely useful for tabular
+// agents.
+func NewTask(e Environment, discretizer *Discretizer) *Task {
+	t := &Task{
+		env:         e,
+		discretizer: discretizer,
+	}
+	for _, id := range e.Actions() {
+		t.actions = append(t.actions, Action(id))
+	}
+	t.Reset()
+	return t
+}
+
+// Reset starts a new episode, and returns the initial state.
+func (t *Task) Reset() *State {
+	t.current = t.newState(t.env.Reset(), false, false)
+	t.reward = 0
+	return t.current
+}
+
+// State returns the current state of the environment.
+func (t *Task) State() *State {
+	return t.current
+}
+
+// Reward returns the reward earned by the most recently applied action.
+func (t *Task) Reward() float64 {
+	return t.reward
+}
+
+func (t *Task) newState(observation []float64, terminal, truncated bool) *State {
+	var id string
+	if t.discretizer != nil {
+		id = t.discretizer.ID(observation)
+	} else {
+		parts := make([]string, len(observation))
+		for i, v := range observation {
+			parts[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		id = strings.Join(parts, ":")
+	}
+	return &State{
+		task:        t,
+		observation: observation,
+		id:          id,
+		terminal:    terminal,
+		truncated:   truncated,
+	}
+}
+
+// RunEpisode resets the task and runs a single episode, in which agent
+// recommends each action. If learn is true, the agent learns from every
+// transition. The episode runs until it terminates or is truncated. The total
+// reward and number of steps for the episode are returned.
+func (t *Task) RunEpisode(agent iface.Agenter, learn bool) (totalReward float64, steps int, err error) {
+	state := t.Reset()
+	for !state.Ended() {
+		action, err := agent.RecommendAction(state)
+		if err != nil {
+			return totalReward, steps, err
+		}
+		if err := agent.Transition(state, action); err != nil {
+			return totalReward, steps, err
+		}
+		next := t.State()
+		if learn {
+			agent.Learn(state, action, next, t.Reward())
+		}
+		totalReward += t.Reward()
+		steps++
+		state = next
+	}
+	return totalReward, steps, nil
+}
+
+// Action is an action that can be applied to a Task's environment.
+type Action string
+
+// ID returns the ID of the action.
+func (a Action) ID() string {
+	return string(a)
+}
+
+// State is a single observation of a Task's environment. States are
+// immutable; applying an action to the current State advances the Task to a
+// new State.
+type State struct {
+	task        *Task
+	observation []float64
+	id          string
+	terminal    bool
+	truncated   bool
+}
+
+// PossibleActions returns every action of the environment. A terminal state
+// has no possible actions.
+func (s *State) PossibleActions() []iface.Actioner {
+	if s.terminal {
+		return []iface.Actioner{}
+	}
+	return s.task.actions
+}
+
+// ActionIsCompatible returns true if the action is one of the environment's
+// actions and the episode has not ended at the state. No action is compatible
+// with a truncated state, since no action can be applied to it.
+func (s *State) ActionIsCompatible(action iface.Actioner) bool {
+	if s.truncated {
+		return false
+	}
+	_, err := s.GetAction(action.ID())
+	return err == nil
+}
+
+// GetAction returns the action with the specified ID.
+func (s *State) GetAction(id string) (iface.Actioner, error) {
+	for _, action := range s.PossibleActions() {
+		if action.ID() == id {
+			return action, nil
+		}
+	}
+	return nil, fmt.Errorf("action '%v' is not possible in state '%v'", id, s.id)
+}
+
+// ID returns the discretized ID of the observation.
+func (s *State) ID() string {
+	return s.id
+}
+
+// Features returns the raw observation.
+func (s *State) Features() []float64 {
+	return append([]float64(nil), s.observation...)
+}
+
+// IsTerminal returns true if the episode terminated at this state, which
+// thus has no future rewards. A state at which the episode was merely
+// truncated is not terminal.
+func (s *State) IsTerminal() bool {
+	return s.terminal
+}
+
+// IsTruncated returns true if the episode was cut short at this state by the
+// environment's limit on the length of an episode. A truncated state still has
+// possible actions (so that agents bootstrap from its value), but no action
+// is compatible with it or can be applied to it.
+func (s *State) IsTruncated() bool {
+	return s.truncated
+}
+
+// Ended returns true if the episode either terminated or was truncated at
+// this state.
+func (s *State) Ended() bool {
+	return s.terminal || s.truncated
+}
+
+// Apply applies an action to the environment, advancing the Task to a new
+// State. An error is returned if the state is not the Task's current state,
+// or if the episode was truncated at the state.
+func (s *State) Apply(action iface.Actioner) error {
+	if s != s.task.current {
+		return fmt.Errorf("state '%v' is not the current state of the task", s.id)
+	}
+	if s.truncated {
+		return fmt.Errorf("the episode was truncated at state '%v'", s.id)
+	}
+	index := -1
+	for i, a := range s.PossibleActions() {
+		if a.ID() == action.ID() {
+			index = i
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("action '%v' is not possible in state '%v'", action.ID(), s.id)
+	}
+	observation, reward, terminated, truncated := s.task.env.Step(index)
+	s.task.current = s.task.newState(observation, terminated, truncated)
+	s.task.reward = reward
+	return nil
+}
+
+var (
+	_ iface.Stater     = (*State)(nil)
+	_ iface.Featurer   = (*State)(nil)
+	_ iface.Terminaler = (*State)(nil)
+)
@@ -0,0 +1,7 @@
+// Package exporter exposes the internals of a qlearning.BayesianAgent (the
+// number of states and state/action pairs, call counts, temporal difference
+// errors, exploration rate, and the distribution of q-values) in the
+// Prometheus text exposition format, via an http.Handler that any
+// Prometheus-compatible scraper can consume. No Prometheus client library or
+// server is required.
+package exporter
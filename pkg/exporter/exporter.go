@@ -0,0 +1,412 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/middleware"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultTDErrorBuckets are the upper bounds of the TD error histogram
+// buckets used if none are configured.
+var DefaultTDErrorBuckets = []float64{-10, -5, -2, -1, -.5, -.1, 0, .1, .5, 1, 2, 5, 10}
+
+// DefaultQValueBuckets are the upper bounds of the q-value distribution
+// buckets used if none are configured.
+var DefaultQValueBuckets = []float64{-10, -5, -2, -1, -.5, 0, .5, 1, 2, 5, 10}
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// Config describes an Exporter.
+type Config struct {
+	// Namespace prefixes the name of every metric. It defaults to
+	// "qlearning".
+	Namespace string
+	// TDErrorBuckets are the ascending upper bounds of the TD error
+	// histogram buckets. They default to DefaultTDErrorBuckets.
+	TDErrorBuckets []float64
+	// QValueBuckets are the ascending upper bounds of the buckets of the
+	// q-value distribution. They default to DefaultQValueBuckets.
+	QValueBuckets []float64
+}
+
+// Exporter collects metrics about a BayesianAgent and serves them in the
+// Prometheus text exposition format.
+//
+// Every metric is maintained as the agent runs, so a scrape never reads the
+// agent or its store, and costs the same however many states the agent has
+// learned. Counts of calls to the agent are collected by the exporter's
+// Middleware. The TD errors, steps, and q-values of the agent's updates are
+// collected by ObserveUpdate, which should be supplied to the agent via
+// qlearning.WithUpdateHook. The exploration rate is read from the agent set by
+// Watch after each call made via the Middleware.
+//
+// The states, state/action pairs, and q-value distribution describe the pairs
+// the agent has learned from. Watch records the pairs the agent has already
+// learned, and subscribes to the states its store evicts (see
+// qlearning.EvictionNotifier); ObserveUpdate records each pair as it is
+// learned. Pairs removed by qlearning.BayesianAgent.PruneActions are only
+// reflected if ObserveRemoval is supplied as its archive function, and a
+// context restored by SetAgentContext or RestoreAgentContext is only
+// reflected once Resync is called. The exporter holds the latest raw q-value
+// of each pair, so its memory grows with their number.
+//
+// The q-value distribution is exported as a gauge, q_value_pairs, with a max
+// label rather than as a histogram, since pairs move between its buckets as
+// their q-values change, so its counts rise and fall.
+//
+// An Exporter is safe for concurrent use.
+type Exporter struct {
+	namespace string
+
+	mu              sync.Mutex
+	agent           *qlearning.BayesianAgent
+	recommendations uint64
+	recommendErrors uint64
+	transitions     uint64
+	transitionErrs  uint64
+	learns          uint64
+	explorationRate float64
+	steps           int
+
+	tdBuckets []float64
+	tdCounts  []uint64
+	tdSum     float64
+	tdCount   uint64
+
+	// values holds the latest raw q-value of each pair, keyed by state ID and
+	// then by action ID, and qCounts the number of pairs in each q-value
+	// bucket (the last of which is unbounded).
+	values   map[string]map[string]float64
+	pairs    int
+	qBuckets []float64
+	qCounts  []int
+}
+
+// NewExporter returns a reference to a new Exporter.
+// An error is returned if the namespace is not a valid metric name, or if any
+// buckets are not in ascending order.
+func NewExporter(c Config) (*Exporter, error) {
+	if c.Namespace == "" {
+		c.Namespace = "qlearning"
+	}
+	if !namePattern.MatchString(c.Namespace) {
+		return nil, fmt.Errorf("namespace '%v' is not a valid metric name", c.Namespace)
+	}
+	if c.TDErrorBuckets == nil {
+		c.TDErrorBuckets = DefaultTDErrorBuckets
+	}
+	if c.QValueBuckets == nil {
+		c.QValueBuckets = DefaultQValueBuckets
+	}
+	if !ascending(c.TDErrorBuckets) {
+		return nil, fmt.Errorf("TD error buckets must be in ascending order")
+	}
+	if !ascending(c.QValueBuckets) {
+		return nil, fmt.Errorf("q-value buckets must be in ascending order")
+	}
+	return &Exporter{
+		namespace: c.Namespace,
+		tdBuckets: append([]float64(nil), c.TDErrorBuckets...),
+		tdCounts:  make([]uint64, len(c.TDErrorBuckets)),
+		values:    make(map[string]map[string]float64),
+		qBuckets:  append([]float64(nil), c.QValueBuckets...),
+		qCounts:   make([]int, len(c.QValueBuckets)+1),
+	}, nil
+}
+
+func ascending(bounds []float64) bool {
+	for i, bound := range bounds {
+		if math.IsNaN(bound) || (i > 0 && bound <= bounds[i-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Watch sets the agent whose exploration rate is exported, records the steps
+// and q-values the agent has already learned (see Resync), and subscribes to
+// the states the agent's store evicts. Watch reads every action the agent has
+// stored, so should be called once, before the agent is in use.
+func (e *Exporter) Watch(agent *qlearning.BayesianAgent) {
+	e.mu.Lock()
+	e.agent = agent
+	e.mu.Unlock()
+	agent.AddEvictListener(e.observeEviction)
+	e.Resync()
+}
+
+// Resync replaces the steps, q-values, and exploration rate the exporter
+// holds with those of the watched agent. It must be called after the agent's
+// context is replaced by SetAgentContext or RestoreAgentContext, and, like
+// those methods, must not be called while the agent is in use. Resync reads
+// every action the agent has stored. If no agent is watched, Resync does
+// nothing.
+func (e *Exporter) Resync() {
+	e.mu.Lock()
+	agent := e.agent
+	e.mu.Unlock()
+	if agent == nil {
+		return
+	}
+	c := agent.GetAgentContext()
+	rate := agent.ExplorationRate()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.explorationRate = rate
+	e.steps = c.Steps
+	e.values = make(map[string]map[string]float64)
+	e.pairs = 0
+	e.qCounts = make([]int, len(e.qBuckets)+1)
+	for stateID, actions := range c.QValues {
+		for actionID, stats := range actions {
+			if stats.Calls() > 0 {
+				e.setValue(stateID, actionID, stats.QValueRaw())
+			}
+		}
+	}
+}
+
+// ObserveUpdate records an update made by the agent. It is intended to be
+// supplied to qlearning.WithUpdateHook.
+func (e *Exporter) ObserveUpdate(u qlearning.Update) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.steps = u.Step + 1
+	e.setValue(u.StateID, u.ActionID, u.QValueRaw)
+	if math.IsNaN(u.TDError) {
+		return
+	}
+	i := sort.SearchFloat64s(e.tdBuckets, u.TDError)
+	if i < len(e.tdCounts) {
+		e.tdCounts[i]++
+	}
+	e.tdSum += u.TDError
+	e.tdCount++
+}
+
+// setValue records the latest raw q-value of a pair, moving the pair between
+// q-value buckets as necessary.
+func (e *Exporter) setValue(stateID, actionID string, value float64) {
+	if math.IsNaN(value) {
+		value = 0
+	}
+	actions, found := e.values[stateID]
+	if !found {
+		actions = make(map[string]float64)
+		e.values[stateID] = actions
+	}
+	if previous, found := actions[actionID]; found {
+		e.qCounts[sort.SearchFloat64s(e.qBuckets, previous)]--
+	} else {
+		e.pairs++
+	}
+	actions[actionID] = value
+	e.qCounts[sort.SearchFloat64s(e.qBuckets, value)]++
+}
+
+// observeEviction records the removal of every action of an evicted state. It
+// is registered with the watched agent by Watch.
+func (e *Exporter) observeEviction(stateID string, actions map[string]iface.ActionStatter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for actionID := range actions {
+		e.removeValue(stateID, actionID)
+	}
+}
+
+// ObserveRemoval records the removal of an action of a state. It is intended
+// to be supplied as the archive function of
+// qlearning.BayesianAgent.PruneActions.
+func (e *Exporter) ObserveRemoval(stateID, actionID string, stats iface.ActionStatter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.removeValue(stateID, actionID)
+}
+
+// removeValue forgets the raw q-value of a pair, if any.
+func (e *Exporter) removeValue(stateID, actionID string) {
+	actions := e.values[stateID]
+	value, found := actions[actionID]
+	if !found {
+		return
+	}
+	e.qCounts[sort.SearchFloat64s(e.qBuckets, value)]--
+	e.pairs--
+	delete(actions, actionID)
+	if len(actions) == 0 {
+		delete(e.values, stateID)
+	}
+}
+
+// Middleware returns Middleware that counts the calls made to the agent, and
+// refreshes the exploration rate of the watched agent after each call.
+//
+// Only the methods of iface.Agenter are counted. Methods of the watched agent
+// that are reached by unwrapping the middleware (such as RecommendDecision or
+// Reward) are not counted, and do not refresh the exploration rate, though any
+// updates they cause are still observed by ObserveUpdate.
+func (e *Exporter) Middleware() middleware.Middleware {
+	return func(next iface.Agenter) iface.Agenter {
+		return &counted{next: next, e: e}
+	}
+}
+
+// counted is an agent that counts each call made to next.
+type counted struct {
+	next iface.Agenter
+	e    *Exporter
+}
+
+func (c *counted) RecommendAction(state iface.Stater) (iface.Actioner, error) {
+	action, err := c.next.RecommendAction(state)
+	c.e.record(func(e *Exporter) {
+		e.recommendations++
+		if err != nil {
+			e.recommendErrors++
+		}
+	})
+	return action, err
+}
+
+func (c *counted) Transition(state iface.Stater, action iface.Actioner) error {
+	err := c.next.Transition(state, action)
+	c.e.record(func(e *Exporter) {
+		e.transitions++
+		if err != nil {
+			e.transitionErrs++
+		}
+	})
+	return err
+}
+
+func (c *counted) Learn(previousState iface.Stater, actionTaken iface.Actioner, currentState iface.Stater, reward float64) {
+	c.next.Learn(previousState, actionTaken, currentState, reward)
+	c.e.record(func(e *Exporter) {
+		e.learns++
+	})
+}
+
+func (c *counted) Unwrap() iface.Agenter {
+	return c.next
+}
+
+// record applies a change to the call counts, and refreshes the exploration
+// rate. It is called on the agent's goroutine, where reading the agent is
+// safe.
+func (e *Exporter) record(count func(e *Exporter)) {
+	e.mu.Lock()
+	agent := e.agent
+	e.mu.Unlock()
+	rate := 0.0
+	if agent != nil {
+		rate = agent.ExplorationRate()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	count(e)
+	e.explorationRate = rate
+}
+
+// ServeHTTP writes every metric in the Prometheus text exposition format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	b := bufio.NewWriter(w)
+	e.write(b)
+	b.Flush()
+}
+
+func (e *Exporter) write(w *bufio.Writer) {
+	e.mu.Lock()
+	counters := []struct {
+		name, help string
+		value      uint64
+	}{
+		{"recommendations_total", "Number of calls to RecommendAction.", e.recommendations},
+		{"recommendation_errors_total", "Number of calls to RecommendAction that returned an error.", e.recommendErrors},
+		{"transitions_total", "Number of calls to Transition.", e.transitions},
+		{"transition_errors_total", "Number of calls to Transition that returned an error.", e.transitionErrs},
+		{"learn_total", "Number of calls to Learn.", e.learns},
+	}
+	gauges := []struct {
+		name, help string
+		value      float64
+	}{
+		{"states", "Number of states with at least one action the agent has learned from.", float64(len(e.values))},
+		{"state_actions", "Number of state/action pairs the agent has learned from.", float64(e.pairs)},
+		{"steps", "Number of steps the agent has learned from.", float64(e.steps)},
+	}
+	watched := e.agent != nil
+	if watched {
+		gauges = append(gauges, struct {
+			name, help string
+			value      float64
+		}{"exploration_rate", "Probability that the agent recommends an action at random.", e.explorationRate})
+	}
+	tdCounts := append([]uint64(nil), e.tdCounts...)
+	tdSum, tdCount := e.tdSum, e.tdCount
+	qCounts := append([]int(nil), e.qCounts...)
+	e.mu.Unlock()
+
+	for _, c := range counters {
+		e.header(w, c.name, c.help, "counter")
+		fmt.Fprintf(w, "%v_%v %v\n", e.namespace, c.name, c.value)
+	}
+
+	e.header(w, "td_error", "Temporal difference error of each q-value update.", "histogram")
+	var cumulative uint64
+	for i, bound := range e.tdBuckets {
+		cumulative += tdCounts[i]
+		fmt.Fprintf(w, "%v_td_error_bucket{le=\"%v\"} %v\n", e.namespace, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%v_td_error_bucket{le=\"+Inf\"} %v\n", e.namespace, tdCount)
+	fmt.Fprintf(w, "%v_td_error_sum %v\n", e.namespace, formatFloat(tdSum))
+	fmt.Fprintf(w, "%v_td_error_count %v\n", e.namespace, tdCount)
+
+	for _, g := range gauges {
+		e.header(w, g.name, g.help, "gauge")
+		fmt.Fprintf(w, "%v_%v %v\n", e.namespace, g.name, formatFloat(g.value))
+	}
+
+	e.header(w, "q_value_pairs", "Number of learned state/action pairs whose raw q-value is at most max.", "gauge")
+	pairs := 0
+	for i, bound := range e.qBuckets {
+		pairs += qCounts[i]
+		fmt.Fprintf(w, "%v_q_value_pairs{max=\"%v\"} %v\n", e.namespace, formatFloat(bound), pairs)
+	}
+	pairs += qCounts[len(e.qBuckets)]
+	fmt.Fprintf(w, "%v_q_value_pairs{max=\"+Inf\"} %v\n", e.namespace, pairs)
+}
+
+func (e *Exporter) header(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %v_%v %v\n", e.namespace, name, help)
+	fmt.Fprintf(w, "# TYPE %v_%v %v\n", e.namespace, name, kind)
+}
+
+// formatFloat formats a value as the exposition format expects.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var _ middleware.Unwrapper = (*counted)(nil)
@@ -0,0 +1,295 @@
+package exporter_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/exporter"
+	"github.com/eltorocorp/reinforcement-learning/pkg/middleware"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(e *exporter.Exporter) (string, string) {
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	return recorder.Header().Get("Content-Type"), recorder.Body.String()
+}
+
+func Test_Exporter(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	x := agent.NewMockActioner(mc)
+	x.EXPECT().ID().Return("X").AnyTimes()
+	y := agent.NewMockActioner(mc)
+	y.EXPECT().ID().Return("Y").AnyTimes()
+	a := agent.NewMockStater(mc)
+	a.EXPECT().ID().Return("A").AnyTimes()
+	a.EXPECT().PossibleActions().Return([]iface.Actioner{x, y}).AnyTimes()
+	a.EXPECT().GetAction("X").Return(x, nil).AnyTimes()
+	terminal := agent.NewMockStater(mc)
+	terminal.EXPECT().ID().Return("T").AnyTimes()
+	terminal.EXPECT().PossibleActions().Return([]iface.Actioner{}).AnyTimes()
+
+	e, err := exporter.NewExporter(exporter.Config{
+		Namespace:      "agent",
+		TDErrorBuckets: []float64{0, 3},
+		QValueBuckets:  []float64{0, 2.5},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithLearningRate(.5),
+		qlearning.WithDiscount(0),
+		qlearning.WithUpdateHook(e.ObserveUpdate),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	e.Watch(ba)
+	wrapped := e.Middleware()(ba)
+	assert.Equal(t, ba, middleware.Unwrap(wrapped))
+
+	wrapped.Learn(a, x, terminal, 4)
+	wrapped.Learn(a, x, terminal, 4)
+	_, err = wrapped.RecommendAction(a)
+	assert.NoError(t, err)
+	_, err = wrapped.RecommendAction(terminal)
+	assert.Error(t, err)
+
+	contentType, body := scrape(e)
+	assert.Equal(t, exporter.ContentType, contentType)
+	for _, line := range []string{
+		"# TYPE agent_recommendations_total counter",
+		"agent_recommendations_total 2",
+		"agent_recommendation_errors_total 1",
+		"agent_transitions_total 0",
+		"agent_learn_total 2",
+		"# TYPE agent_td_error histogram",
+		`agent_td_error_bucket{le="0"} 0`,
+		`agent_td_error_bucket{le="3"} 1`,
+		`agent_td_error_bucket{le="+Inf"} 2`,
+		"agent_td_error_sum 6",
+		"agent_td_error_count 2",
+		"# TYPE agent_states gauge",
+		"agent_states 1",
+		"agent_state_actions 1",
+		"agent_steps 2",
+		"agent_exploration_rate 0",
+		"# TYPE agent_q_value_pairs gauge",
+		`agent_q_value_pairs{max="0"} 0`,
+		`agent_q_value_pairs{max="2.5"} 0`,
+		`agent_q_value_pairs{max="+Inf"} 1`,
+	} {
+		assert.Contains(t, strings.Split(body, "\n"), line)
+	}
+}
+
+func Test_ExporterUnwatched(t *testing.T) {
+	e, err := exporter.NewExporter(exporter.Config{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, body := scrape(e)
+	assert.Contains(t, body, "qlearning_learn_total 0\n")
+	assert.Contains(t, body, `qlearning_td_error_bucket{le="-10"} 0`)
+	assert.Contains(t, body, "qlearning_states 0\n")
+	assert.NotContains(t, body, "qlearning_exploration_rate")
+}
+
+func Test_ExporterWatchRecordsLearnedValues(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	x := agent.NewMockActioner(mc)
+	x.EXPECT().ID().Return("X").AnyTimes()
+	a := agent.NewMockStater(mc)
+	a.EXPECT().ID().Return("A").AnyTimes()
+	a.EXPECT().PossibleActions().Return([]iface.Actioner{x}).AnyTimes()
+	terminal := agent.NewMockStater(mc)
+	terminal.EXPECT().ID().Return("T").AnyTimes()
+	terminal.EXPECT().PossibleActions().Return([]iface.Actioner{}).AnyTimes()
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithExploration(qlearning.ConstantRate(.25)),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, x, terminal, -1)
+
+	e, err := exporter.NewExporter(exporter.Config{QValueBuckets: []float64{0}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	e.Watch(ba)
+	_, body := scrape(e)
+	for _, line := range []string{
+		"qlearning_states 1",
+		"qlearning_state_actions 1",
+		"qlearning_steps 1",
+		"qlearning_exploration_rate 0.25",
+		`qlearning_q_value_pairs{max="0"} 1`,
+		`qlearning_q_value_pairs{max="+Inf"} 1`,
+	} {
+		assert.Contains(t, strings.Split(body, "\n"), line)
+	}
+}
+
+func Test_ExporterObservesRemovals(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	x := agent.NewMockActioner(mc)
+	x.EXPECT().ID().Return("X").AnyTimes()
+	states := map[string]*agent.MockStater{}
+	for _, id := range []string{"A", "B", "C"} {
+		state := agent.NewMockStater(mc)
+		state.EXPECT().ID().Return(id).AnyTimes()
+		states[id] = state
+	}
+	states["A"].EXPECT().PossibleActions().Return([]iface.Actioner{x}).AnyTimes()
+	states["B"].EXPECT().PossibleActions().Return([]iface.Actioner{x}).AnyTimes()
+	states["C"].EXPECT().PossibleActions().Return([]iface.Actioner{}).AnyTimes()
+	terminal := agent.NewMockStater(mc)
+	terminal.EXPECT().ID().Return("T").AnyTimes()
+	terminal.EXPECT().PossibleActions().Return([]iface.Actioner{}).AnyTimes()
+
+	e, err := exporter.NewExporter(exporter.Config{QValueBuckets: []float64{0}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	store, err := qlearning.NewBoundedStore(qlearning.EvictionPolicy{MaxStates: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithStore(store),
+		qlearning.WithUpdateHook(e.ObserveUpdate),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	e.Watch(ba)
+
+	// Learning from C evicts A.
+	ba.Learn(states["A"], x, terminal, 1)
+	ba.Learn(states["B"], x, terminal, 1)
+	ba.Learn(states["C"], x, terminal, 1)
+	_, body := scrape(e)
+	assert.Contains(t, strings.Split(body, "\n"), "qlearning_states 2")
+
+	// C no longer offers X, so X is pruned.
+	assert.Equal(t, []string{"X"}, ba.PruneActions(states["C"], e.ObserveRemoval))
+	_, body = scrape(e)
+	for _, line := range []string{
+		"qlearning_states 1",
+		"qlearning_state_actions 1",
+		`qlearning_q_value_pairs{max="+Inf"} 1`,
+	} {
+		assert.Contains(t, strings.Split(body, "\n"), line)
+	}
+}
+
+func Test_ExporterResync(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	x := agent.NewMockActioner(mc)
+	x.EXPECT().ID().Return("X").AnyTimes()
+	a := agent.NewMockStater(mc)
+	a.EXPECT().ID().Return("A").AnyTimes()
+	a.EXPECT().PossibleActions().Return([]iface.Actioner{x}).AnyTimes()
+	terminal := agent.NewMockStater(mc)
+	terminal.EXPECT().ID().Return("T").AnyTimes()
+	terminal.EXPECT().PossibleActions().Return([]iface.Actioner{}).AnyTimes()
+
+	e, err := exporter.NewExporter(exporter.Config{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithUpdateHook(e.ObserveUpdate))
+	if !assert.NoError(t, err) {
+		return
+	}
+	e.Watch(ba)
+	ba.Learn(a, x, terminal, 1)
+	_, body := scrape(e)
+	assert.Contains(t, strings.Split(body, "\n"), "qlearning_state_actions 1")
+
+	ba.SetAgentContext(qlearning.AgentContext{
+		LearningRate: 1,
+		QValues:      map[string]map[string]iface.ActionStatter{},
+	})
+	e.Resync()
+	_, body = scrape(e)
+	for _, line := range []string{
+		"qlearning_states 0",
+		"qlearning_state_actions 0",
+		"qlearning_steps 0",
+		`qlearning_q_value_pairs{max="+Inf"} 0`,
+	} {
+		assert.Contains(t, strings.Split(body, "\n"), line)
+	}
+}
+
+func Test_NewExporterValidation(t *testing.T) {
+	_, err := exporter.NewExporter(exporter.Config{Namespace: "not valid"})
+	assert.EqualError(t, err, "namespace 'not valid' is not a valid metric name")
+	_, err = exporter.NewExporter(exporter.Config{TDErrorBuckets: []float64{1, 1}})
+	assert.EqualError(t, err, "TD error buckets must be in ascending order")
+	_, err = exporter.NewExporter(exporter.Config{QValueBuckets: []float64{2, 1}})
+	assert.EqualError(t, err, "q-value buckets must be in ascending order")
+}
+
+func Test_ExporterScrapesConcurrently(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	x := agent.NewMockActioner(mc)
+	x.EXPECT().ID().Return("X").AnyTimes()
+	a := agent.NewMockStater(mc)
+	a.EXPECT().ID().Return("A").AnyTimes()
+	a.EXPECT().PossibleActions().Return([]iface.Actioner{x}).AnyTimes()
+	a.EXPECT().GetAction("X").Return(x, nil).AnyTimes()
+
+	e, err := exporter.NewExporter(exporter.Config{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithUpdateHook(e.ObserveUpdate))
+	if !assert.NoError(t, err) {
+		return
+	}
+	e.Watch(ba)
+	wrapped := e.Middleware()(ba)
+
+	// Scrapes do not read the agent, so they may run while the agent learns.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			scrape(e)
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		action, err := wrapped.RecommendAction(a)
+		if assert.NoError(t, err) {
+			wrapped.Learn(a, action, a, 1)
+		}
+	}
+	<-done
+
+	_, body := scrape(e)
+	assert.Contains(t, body, "qlearning_learn_total 200\n")
+	assert.Contains(t, body, "qlearning_td_error_count 200\n")
+}
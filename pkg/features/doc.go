@@ -0,0 +1,4 @@
+// Package features provides constructors that turn continuous observations
+// (prices, budgets, times, etc.) into feature vectors suitable for the
+// function approximation agents in this module.
+package features
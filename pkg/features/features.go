@@ -0,0 +1,72 @@
+package features
+
+import (
+	"fmt"
+	"math"
+)
+
+// Constructor is anything that can construct a dense feature vector from an
+// observation.
+type Constructor interface {
+	// Size returns the length of the feature vectors produced by the
+	// constructor.
+	Size() int
+
+	// Features returns the feature vector for an observation.
+	Features(observation []float64) ([]float64, error)
+}
+
+// Bounds describes the expected range of each dimension of an observation.
+// Values that fall outside of the bounds are clipped to the nearest bound.
+type Bounds struct {
+	Low  []float64
+	High []float64
+}
+
+// Dimensions returns the number of dimensions described by the bounds.
+func (b Bounds) Dimensions() int {
+	return len(b.Low)
+}
+
+func (b Bounds) validate() error {
+	if len(b.Low) == 0 {
+		return fmt.Errorf("bounds must describe at least one dimension")
+	}
+	if len(b.Low) != len(b.High) {
+		return fmt.Errorf("bounds have %v low values and %v high values", len(b.Low), len(b.High))
+	}
+	for i := range b.Low {
+		if !(b.High[i] > b.Low[i]) {
+			return fmt.Errorf("bounds for dimension %v are empty: [%v, %v]", i, b.Low[i], b.High[i])
+		}
+	}
+	return nil
+}
+
+// normalize scales each dimension of an observation to the range [0, 1].
+func (b Bounds) normalize(observation []float64) ([]float64, error) {
+	if len(observation) != len(b.Low) {
+		return nil, fmt.Errorf("expected observation with %v dimensions, got %v", len(b.Low), len(observation))
+	}
+	scaled := make([]float64, len(observation))
+	for i, v := range observation {
+		scaled[i] = math.Min(math.Max((v-b.Low[i])/(b.High[i]-b.Low[i]), 0), 1)
+	}
+	return scaled, nil
+}
+
+// Sparse is a feature vector in which only the listed indices are non-zero.
+type Sparse struct {
+	Size    int
+	Indices []int
+	Values  []float64
+}
+
+// Dense returns the feature vector with every element present.
+func (s Sparse) Dense() []float64 {
+	dense := make([]float64, s.Size)
+	for i, index := range s.Indices {
+		dense[index] += s.Values[i]
+	}
+	return dense
+}
@@ -0,0 +1,105 @@
+package features_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/features"
+	"github.com/stretchr/testify/assert"
+)
+
+var unitSquare = features.Bounds{Low: []float64{0, 0}, High: []float64{1, 1}}
+
+func Test_SparseDense(t *testing.T) {
+	s := features.Sparse{Size: 4, Indices: []int{1, 3, 1}, Values: []float64{1, 2, 1}}
+	assert.Equal(t, []float64{0, 2, 0, 2}, s.Dense())
+}
+
+func Test_InvalidBounds(t *testing.T) {
+	testCases := []struct {
+		name   string
+		bounds features.Bounds
+		exp    string
+	}{
+		{"empty", features.Bounds{}, "bounds must describe at least one dimension"},
+		{"mismatched", features.Bounds{Low: []float64{0}, High: []float64{1, 2}}, "bounds have 1 low values and 2 high values"},
+		{"inverted", features.Bounds{Low: []float64{1}, High: []float64{0}}, "bounds for dimension 0 are empty: [1, 0]"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := features.NewFourier(tc.bounds, 1)
+			assert.EqualError(t, err, tc.exp)
+		})
+	}
+}
+
+func Test_TileCoder(t *testing.T) {
+	tc, err := features.NewTileCoder(unitSquare, 8, 4, 1024)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1024, tc.Size())
+
+	a, err := tc.Encode([]float64{.5, .5})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, a.Indices, 8)
+
+	again, _ := tc.Encode([]float64{.5, .5})
+	assert.Equal(t, a, again, "encoding should be deterministic")
+
+	near, _ := tc.Encode([]float64{.52, .5})
+	far, _ := tc.Encode([]float64{.05, .95})
+	assert.True(t, overlap(a, near) > overlap(a, far))
+
+	clipped, _ := tc.Encode([]float64{5, -5})
+	edge, _ := tc.Encode([]float64{1, 0})
+	assert.Equal(t, edge, clipped, "out of bound values should be clipped")
+
+	_, err = tc.Encode([]float64{1})
+	assert.EqualError(t, err, "expected observation with 2 dimensions, got 1")
+}
+
+func overlap(a, b features.Sparse) (n int) {
+	for _, i := range a.Indices {
+		for _, j := range b.Indices {
+			if i == j {
+				n++
+				break
+			}
+		}
+	}
+	return
+}
+
+func Test_RBF(t *testing.T) {
+	r, err := features.NewRBF(unitSquare, 3, .25)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 9, r.Size())
+
+	f, err := r.Features([]float64{0, 0})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1.0, f[0])
+		assert.InDelta(t, math.Exp(-.25/(2*.0625)), f[1], 1e-12)
+		assert.True(t, f[8] < f[4])
+	}
+
+	_, err = features.NewRBFWithCenters(unitSquare, [][]float64{{0}}, 1)
+	assert.EqualError(t, err, "center 0 has 1 dimensions, expected 2")
+}
+
+func Test_Fourier(t *testing.T) {
+	f, err := features.NewFourier(features.Bounds{Low: []float64{-1}, High: []float64{1}}, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 3, f.Size())
+
+	v, err := f.Features([]float64{0})
+	if assert.NoError(t, err) {
+		assert.InDeltaSlice(t, []float64{1, 0, -1}, v, 1e-12)
+	}
+}
@@ -0,0 +1,67 @@
+package features
+
+import (
+	"fmt"
+	"math"
+)
+
+// Fourier constructs dense features from the Fourier cosine basis of a given
+// order. Each feature is cos(π c·x), where x is the observation scaled to
+// [0, 1] by the bounds, and c is one of the (order+1)^d integer coefficient
+// vectors whose elements range from 0 to order.
+//
+// see http://incompleteideas.net/book/RLbook2020.pdf (Section 9.5.2)
+type Fourier struct {
+	bounds       Bounds
+	coefficients [][]float64
+}
+
+// NewFourier returns a reference to a new Fourier basis of the specified
+// order.
+func NewFourier(bounds Bounds, order int) (*Fourier, error) {
+	if err := bounds.validate(); err != nil {
+		return nil, err
+	}
+	if order < 0 {
+		return nil, fmt.Errorf("order must not be negative")
+	}
+
+	coefficients := [][]float64{{}}
+	for d := 0; d < bounds.Dimensions(); d++ {
+		next := make([][]float64, 0, len(coefficients)*(order+1))
+		for _, c := range coefficients {
+			for k := 0; k <= order; k++ {
+				next = append(next, append(append([]float64(nil), c...), float64(k)))
+			}
+		}
+		coefficients = next
+	}
+	return &Fourier{
+		bounds:       bounds,
+		coefficients: coefficients,
+	}, nil
+}
+
+// Size returns the length of the feature vectors produced by the basis.
+func (f *Fourier) Size() int {
+	return len(f.coefficients)
+}
+
+// Features returns the Fourier basis features for an observation.
+func (f *Fourier) Features(observation []float64) ([]float64, error) {
+	scaled, err := f.bounds.normalize(observation)
+	if err != nil {
+		return nil, err
+	}
+	features := make([]float64, len(f.coefficients))
+	for i, c := range f.coefficients {
+		sum := 0.0
+		for d := range c {
+			sum += c[d] * scaled[d]
+		}
+		features[i] = math.Cos(math.Pi * sum)
+	}
+	return features, nil
+}
+
+var _ Constructor = (*Fourier)(nil)
@@ -0,0 +1,95 @@
+package features
+
+import (
+	"fmt"
+	"math"
+)
+
+// RBF constructs dense features from the distance between an observation and
+// a set of centers, using a Gaussian radial basis function. Distances are
+// measured after each dimension has been scaled to [0, 1] by the bounds.
+type RBF struct {
+	bounds  Bounds
+	centers [][]float64
+	width   float64
+}
+
+// NewRBF returns a reference to a new RBF whose centers are spaced evenly on
+// a grid spanning the bounds, with centersPerDim centers in each dimension.
+// width is the standard deviation of each Gaussian, relative to the
+// normalized range of each dimension.
+func NewRBF(bounds Bounds, centersPerDim int, width float64) (*RBF, error) {
+	if err := bounds.validate(); err != nil {
+		return nil, err
+	}
+	if centersPerDim < 1 {
+		return nil, fmt.Errorf("centersPerDim must be positive")
+	}
+
+	positions := make([]float64, centersPerDim)
+	for i := range positions {
+		if centersPerDim > 1 {
+			positions[i] = float64(i) / float64(centersPerDim-1)
+		} else {
+			positions[i] = .5
+		}
+	}
+
+	centers := [][]float64{{}}
+	for d := 0; d < bounds.Dimensions(); d++ {
+		next := make([][]float64, 0, len(centers)*centersPerDim)
+		for _, c := range centers {
+			for _, p := range positions {
+				next = append(next, append(append([]float64(nil), c...), p))
+			}
+		}
+		centers = next
+	}
+	return NewRBFWithCenters(bounds, centers, width)
+}
+
+// NewRBFWithCenters returns a reference to a new RBF using the supplied
+// centers. Each center is expressed in normalized coordinates, where each
+// dimension ranges from 0 to 1.
+func NewRBFWithCenters(bounds Bounds, centers [][]float64, width float64) (*RBF, error) {
+	if err := bounds.validate(); err != nil {
+		return nil, err
+	}
+	if !(width > 0) {
+		return nil, fmt.Errorf("width must be positive")
+	}
+	for i, c := range centers {
+		if len(c) != bounds.Dimensions() {
+			return nil, fmt.Errorf("center %v has %v dimensions, expected %v", i, len(c), bounds.Dimensions())
+		}
+	}
+	return &RBF{
+		bounds:  bounds,
+		centers: centers,
+		width:   width,
+	}, nil
+}
+
+// Size returns the length of the feature vectors produced by the RBF.
+func (r *RBF) Size() int {
+	return len(r.centers)
+}
+
+// Features returns the activation of each center for an observation.
+func (r *RBF) Features(observation []float64) ([]float64, error) {
+	scaled, err := r.bounds.normalize(observation)
+	if err != nil {
+		return nil, err
+	}
+	features := make([]float64, len(r.centers))
+	for i, c := range r.centers {
+		distance := 0.0
+		for d := range c {
+			distance += (scaled[d] - c[d]) * (scaled[d] - c[d])
+		}
+		features[i] = math.Exp(-distance / (2 * r.width * r.width))
+	}
+	return features, nil
+}
+
+var _ Constructor = (*RBF)(nil)
@@ -0,0 +1,109 @@
+package features
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// TileCoder constructs sparse binary features by overlaying several offset
+// grids (tilings) on the observation space. Each tiling contributes exactly
+// one active tile, so every feature vector has one non-zero element per
+// tiling.
+//
+// Rather than allocating a feature for every tile of every tiling, which grows
+// exponentially with the number of dimensions, tiles are hashed into a table
+// of a fixed size. Collisions are possible, and are generally harmless when
+// the table is reasonably large relative to the number of tiles that are
+// actually visited.
+//
+// see http://incompleteideas.net/book/RLbook2020.pdf (Section 9.5.4)
+type TileCoder struct {
+	bounds      Bounds
+	tilings     int
+	tilesPerDim int
+	size        int
+}
+
+// NewTileCoder returns a reference to a new TileCoder.
+//
+// bounds:
+//
+//	The expected range of each dimension of an observation.
+//
+// tilings:
+//
+//	The number of offset tilings. More tilings result in finer
+//	generalisation.
+//
+// tilesPerDim:
+//
+//	The number of tiles that span each dimension of a single tiling.
+//
+// size:
+//
+//	The size of the hash table, and thus the length of the feature vectors.
+func NewTileCoder(bounds Bounds, tilings, tilesPerDim, size int) (*TileCoder, error) {
+	if err := bounds.validate(); err != nil {
+		return nil, err
+	}
+	if tilings < 1 || tilesPerDim < 1 || size < 1 {
+		return nil, fmt.Errorf("tilings, tilesPerDim, and size must be positive")
+	}
+	return &TileCoder{
+		bounds:      bounds,
+		tilings:     tilings,
+		tilesPerDim: tilesPerDim,
+		size:        size,
+	}, nil
+}
+
+// Size returns the length of the feature vectors produced by the TileCoder.
+func (tc *TileCoder) Size() int {
+	return tc.size
+}
+
+// Encode returns the active tiles for an observation as a sparse feature
+// vector.
+func (tc *TileCoder) Encode(observation []float64) (Sparse, error) {
+	scaled, err := tc.bounds.normalize(observation)
+	if err != nil {
+		return Sparse{}, err
+	}
+
+	sparse := Sparse{
+		Size:    tc.size,
+		Indices: make([]int, tc.tilings),
+		Values:  make([]float64, tc.tilings),
+	}
+	buf := make([]byte, 8)
+	for t := 0; t < tc.tilings; t++ {
+		h := fnv.New64a()
+		binary.LittleEndian.PutUint64(buf, uint64(t))
+		h.Write(buf)
+		for d, v := range scaled {
+			// Each tiling is displaced asymmetrically in each dimension, as
+			// recommended by Sutton and Barto.
+			offset := float64(t*(2*d+1)%tc.tilings) / float64(tc.tilings)
+			coord := int64(math.Floor(v*float64(tc.tilesPerDim) + offset))
+			binary.LittleEndian.PutUint64(buf, uint64(coord))
+			h.Write(buf)
+		}
+		sparse.Indices[t] = int(h.Sum64() % uint64(tc.size))
+		sparse.Values[t] = 1
+	}
+	return sparse, nil
+}
+
+// Features returns the active tiles for an observation as a dense feature
+// vector.
+func (tc *TileCoder) Features(observation []float64) ([]float64, error) {
+	sparse, err := tc.Encode(observation)
+	if err != nil {
+		return nil, err
+	}
+	return sparse.Dense(), nil
+}
+
+var _ Constructor = (*TileCoder)(nil)
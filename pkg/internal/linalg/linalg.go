@@ -0,0 +1,255 @@
+// Package linalg provides the small amount of dense linear algebra required by
+// the linear learners in this module. It is intentionally minimal; matrices are
+// expected to be small (tens to low hundreds of dimensions).
+package linalg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrSingular is returned when a matrix cannot be inverted.
+var ErrSingular = errors.New("matrix is singular")
+
+// Matrix is a dense, row-major matrix.
+type Matrix struct {
+	Rows int
+	Cols int
+	Data []float64
+}
+
+// NewMatrix returns a zero-valued matrix of the specified dimensions.
+func NewMatrix(rows, cols int) *Matrix {
+	return &Matrix{
+		Rows: rows,
+		Cols: cols,
+		Data: make([]float64, rows*cols),
+	}
+}
+
+// Identity returns an n by n identity matrix.
+func Identity(n int) *Matrix {
+	m := NewMatrix(n, n)
+	for i := 0; i < n; i++ {
+		m.Set(i, i, 1)
+	}
+	return m
+}
+
+// At returns the value at row i, column j.
+func (m *Matrix) At(i, j int) float64 {
+	return m.Data[i*m.Cols+j]
+}
+
+// Set sets the value at row i, column j.
+func (m *Matrix) Set(i, j int, v float64) {
+	m.Data[i*m.Cols+j] = v
+}
+
+// Clone returns a deep copy of the matrix.
+func (m *Matrix) Clone() *Matrix {
+	c := NewMatrix(m.Rows, m.Cols)
+	copy(c.Data, m.Data)
+	return c
+}
+
+// T returns the transpose of the matrix.
+func (m *Matrix) T() *Matrix {
+	t := NewMatrix(m.Cols, m.Rows)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			t.Set(j, i, m.At(i, j))
+		}
+	}
+	return t
+}
+
+// Mul returns the matrix product m*n.
+func (m *Matrix) Mul(n *Matrix) *Matrix {
+	if m.Cols != n.Rows {
+		panic(fmt.Sprintf("linalg: cannot multiply %vx%v by %vx%v", m.Rows, m.Cols, n.Rows, n.Cols))
+	}
+	p := NewMatrix(m.Rows, n.Cols)
+	for i := 0; i < m.Rows; i++ {
+		for k := 0; k < m.Cols; k++ {
+			a := m.At(i, k)
+			if a == 0 {
+				continue
+			}
+			for j := 0; j < n.Cols; j++ {
+				p.Data[i*p.Cols+j] += a * n.At(k, j)
+			}
+		}
+	}
+	return p
+}
+
+// MulVec returns the product of the matrix and the column vector v.
+func (m *Matrix) MulVec(v []float64) []float64 {
+	if m.Cols != len(v) {
+		panic(fmt.Sprintf("linalg: cannot multiply %vx%v by vector of length %v", m.Rows, m.Cols, len(v)))
+	}
+	out := make([]float64, m.Rows)
+	for i := 0; i < m.Rows; i++ {
+		out[i] = Dot(m.Data[i*m.Cols:(i+1)*m.Cols], v)
+	}
+	return out
+}
+
+// Add adds n to m in place.
+func (m *Matrix) Add(n *Matrix) {
+	m.AddScaled(n, 1)
+}
+
+// Sub subtracts n from m in place.
+func (m *Matrix) Sub(n *Matrix) {
+	m.AddScaled(n, -1)
+}
+
+// AddScaled adds s*n to m in place.
+func (m *Matrix) AddScaled(n *Matrix, s float64) {
+	if m.Rows != n.Rows || m.Cols != n.Cols {
+		panic(fmt.Sprintf("linalg: cannot add %vx%v to %vx%v", n.Rows, n.Cols, m.Rows, m.Cols))
+	}
+	for i := range m.Data {
+		m.Data[i] += s * n.Data[i]
+	}
+}
+
+// Outer returns the outer product of u and v (u * v^T).
+func Outer(u, v []float64) *Matrix {
+	m := NewMatrix(len(u), len(v))
+	for i, a := range u {
+		for j, b := range v {
+			m.Set(i, j, a*b)
+		}
+	}
+	return m
+}
+
+// Dot returns the inner product of u and v.
+func Dot(u, v []float64) float64 {
+	if len(u) != len(v) {
+		panic(fmt.Sprintf("linalg: cannot take dot product of vectors of length %v and %v", len(u), len(v)))
+	}
+	sum := 0.0
+	for i := range u {
+		sum += u[i] * v[i]
+	}
+	return sum
+}
+
+// AddVec adds s*v to u in place.
+func AddVec(u, v []float64, s float64) {
+	if len(u) != len(v) {
+		panic(fmt.Sprintf("linalg: cannot add vectors of length %v and %v", len(u), len(v)))
+	}
+	for i := range u {
+		u[i] += s * v[i]
+	}
+}
+
+// QuadForm returns x^T * m * y.
+func QuadForm(x []float64, m *Matrix, y []float64) float64 {
+	return Dot(x, m.MulVec(y))
+}
+
+// ShermanMorrison updates inv in place such that, if inv was the inverse of
+// some matrix A, it becomes the inverse of A + u*v^T. This avoids a full
+// re-inversion when A receives a rank-one update.
+// see https://en.wikipedia.org/wiki/Sherman%E2%80%93Morrison_formula
+func ShermanMorrison(inv *Matrix, u, v []float64) error {
+	invU := inv.MulVec(u)
+	vInv := inv.T().MulVec(v)
+	denominator := 1 + Dot(v, invU)
+	if denominator == 0 || math.IsNaN(denominator) {
+		return ErrSingular
+	}
+	for i := 0; i < inv.Rows; i++ {
+		for j := 0; j < inv.Cols; j++ {
+			inv.Data[i*inv.Cols+j] -= invU[i] * vInv[j] / denominator
+		}
+	}
+	return nil
+}
+
+// Inverse returns the inverse of a square matrix using Gauss-Jordan
+// elimination with partial pivoting.
+func Inverse(m *Matrix) (*Matrix, error) {
+	if m.Rows != m.Cols {
+		return nil, fmt.Errorf("linalg: cannot invert non-square %vx%v matrix", m.Rows, m.Cols)
+	}
+	n := m.Rows
+	a := m.Clone()
+	inv := Identity(n)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a.At(row, col)) > math.Abs(a.At(pivot, col)) {
+				pivot = row
+			}
+		}
+		if math.Abs(a.At(pivot, col)) < 1e-12 {
+			return nil, ErrSingular
+		}
+		a.swapRows(col, pivot)
+		inv.swapRows(col, pivot)
+
+		p := a.At(col, col)
+		for j := 0; j < n; j++ {
+			a.Set(col, j, a.At(col, j)/p)
+			inv.Set(col, j, inv.At(col, j)/p)
+		}
+		for row := 0; row < n; row++ {
+			if row == col {
+				continue
+			}
+			f := a.At(row, col)
+			if f == 0 {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				a.Set(row, j, a.At(row, j)-f*a.At(col, j))
+				inv.Set(row, j, inv.At(row, j)-f*inv.At(col, j))
+			}
+		}
+	}
+	return inv, nil
+}
+
+func (m *Matrix) swapRows(i, j int) {
+	if i == j {
+		return
+	}
+	for k := 0; k < m.Cols; k++ {
+		m.Data[i*m.Cols+k], m.Data[j*m.Cols+k] = m.Data[j*m.Cols+k], m.Data[i*m.Cols+k]
+	}
+}
+
+// FromRows returns a matrix built from a slice of rows. All rows must be of
+// equal length.
+func FromRows(rows [][]float64) (*Matrix, error) {
+	if len(rows) == 0 {
+		return NewMatrix(0, 0), nil
+	}
+	m := NewMatrix(len(rows), len(rows[0]))
+	for i, row := range rows {
+		if len(row) != m.Cols {
+			return nil, fmt.Errorf("linalg: row %v has length %v, expected %v", i, len(row), m.Cols)
+		}
+		copy(m.Data[i*m.Cols:], row)
+	}
+	return m, nil
+}
+
+// ToRows returns a copy of the matrix as a slice of rows. This is a convenient
+// form for serialization.
+func (m *Matrix) ToRows() [][]float64 {
+	rows := make([][]float64, m.Rows)
+	for i := range rows {
+		rows[i] = make([]float64, m.Cols)
+		copy(rows[i], m.Data[i*m.Cols:(i+1)*m.Cols])
+	}
+	return rows
+}
@@ -0,0 +1,56 @@
+package linalg_test
+
+import (
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/internal/linalg"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Mul(t *testing.T) {
+	a := &linalg.Matrix{Rows: 2, Cols: 3, Data: []float64{1, 2, 3, 4, 5, 6}}
+	b := &linalg.Matrix{Rows: 3, Cols: 2, Data: []float64{7, 8, 9, 10, 11, 12}}
+	act := a.Mul(b)
+	assert.Equal(t, []float64{58, 64, 139, 154}, act.Data)
+}
+
+func Test_MulVec(t *testing.T) {
+	a := &linalg.Matrix{Rows: 2, Cols: 2, Data: []float64{1, 2, 3, 4}}
+	assert.Equal(t, []float64{5, 11}, a.MulVec([]float64{1, 2}))
+}
+
+func Test_Inverse(t *testing.T) {
+	a := &linalg.Matrix{Rows: 2, Cols: 2, Data: []float64{4, 7, 2, 6}}
+	inv, err := linalg.Inverse(a)
+	if assert.NoError(t, err) {
+		assert.InDeltaSlice(t, []float64{0.6, -0.7, -0.2, 0.4}, inv.Data, 1e-12)
+	}
+}
+
+func Test_InverseSingular(t *testing.T) {
+	a := &linalg.Matrix{Rows: 2, Cols: 2, Data: []float64{1, 2, 2, 4}}
+	_, err := linalg.Inverse(a)
+	assert.Equal(t, linalg.ErrSingular, err)
+}
+
+func Test_ShermanMorrison(t *testing.T) {
+	a := &linalg.Matrix{Rows: 2, Cols: 2, Data: []float64{4, 7, 2, 6}}
+	u := []float64{1, 2}
+	v := []float64{3, -1}
+
+	inv, err := linalg.Inverse(a)
+	if !assert.NoError(t, err) {
+		return
+	}
+	err = linalg.ShermanMorrison(inv, u, v)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	updated := a.Clone()
+	updated.Add(linalg.Outer(u, v))
+	exp, err := linalg.Inverse(updated)
+	if assert.NoError(t, err) {
+		assert.InDeltaSlice(t, exp.Data, inv.Data, 1e-12)
+	}
+}
@@ -0,0 +1,5 @@
+// Package middleware provides composable decorators for any iface.Agenter,
+// which add behavior such as logging, metrics, recording, and invariant
+// checking without modifying the wrapped agent, in the same manner as
+// http.Handler middleware.
+package middleware
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Invariants returns Middleware that enforces the contract of iface.Agenter
+// around the agent:
+//
+//   - A recommended action must be one of the state's possible actions.
+//     Otherwise, RecommendAction returns an error in place of the action.
+//   - Learn must be supplied a current state and a finite reward whenever it
+//     is supplied a previous state and action. Otherwise, the call is not
+//     passed to the agent, rather than allowing it to panic or corrupt its
+//     model.
+//
+// Each violation is also passed to report, if it is not nil.
+func Invariants(report func(violation error)) Middleware {
+	if report == nil {
+		report = func(error) {}
+	}
+	return func(next iface.Agenter) iface.Agenter {
+		w := wrap(next)
+		w.recommend = func(state iface.Stater) (iface.Actioner, error) {
+			action, err := next.RecommendAction(state)
+			if err != nil {
+				return nil, err
+			}
+			if action == nil {
+				violation := fmt.Errorf("agent recommended no action for state '%v'", state.ID())
+				report(violation)
+				return nil, violation
+			}
+			for _, possible := range state.PossibleActions() {
+				if possible.ID() == action.ID() {
+					return action, nil
+				}
+			}
+			violation := fmt.Errorf("agent recommended action '%v', which is not possible in state '%v'", action.ID(), state.ID())
+			report(violation)
+			return nil, violation
+		}
+		w.learn = func(previousState iface.Stater, actionTaken iface.Actioner, currentState iface.Stater, reward float64) {
+			if previousState != nil && actionTaken != nil {
+				if currentState == nil {
+					report(fmt.Errorf("learn called without a current state after state '%v'", previousState.ID()))
+					return
+				}
+				if math.IsNaN(reward) || math.IsInf(reward, 0) {
+					report(fmt.Errorf("learn called with reward %v for action '%v' of state '%v'", reward, actionTaken.ID(), previousState.ID()))
+					return
+				}
+			}
+			next.Learn(previousState, actionTaken, currentState, reward)
+		}
+		return w
+	}
+}
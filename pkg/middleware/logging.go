@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Logger is satisfied by *log.Logger, and by most structured loggers'
+// printf-style adapters.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Logging returns Middleware that logs every call to the agent, along with
+// its outcome and duration.
+func Logging(logger Logger) Middleware {
+	return func(next iface.Agenter) iface.Agenter {
+		w := wrap(next)
+		w.recommend = func(state iface.Stater) (iface.Actioner, error) {
+			start := time.Now()
+			action, err := next.RecommendAction(state)
+			if err != nil {
+				logger.Printf("RecommendAction state=%v error=%q duration=%v", idOf(state), err, time.Since(start))
+			} else {
+				logger.Printf("RecommendAction state=%v action=%v duration=%v", idOf(state), idOf(action), time.Since(start))
+			}
+			return action, err
+		}
+		w.transition = func(state iface.Stater, action iface.Actioner) error {
+			start := time.Now()
+			err := next.Transition(state, action)
+			if err != nil {
+				logger.Printf("Transition state=%v action=%v error=%q duration=%v", idOf(state), idOf(action), err, time.Since(start))
+			} else {
+				logger.Printf("Transition state=%v action=%v duration=%v", idOf(state), idOf(action), time.Since(start))
+			}
+			return err
+		}
+		w.learn = func(previousState iface.Stater, actionTaken iface.Actioner, currentState iface.Stater, reward float64) {
+			start := time.Now()
+			next.Learn(previousState, actionTaken, currentState, reward)
+			logger.Printf("Learn previous=%v action=%v current=%v reward=%v duration=%v",
+				idOf(previousState), idOf(actionTaken), idOf(currentState), reward, time.Since(start))
+		}
+		return w
+	}
+}
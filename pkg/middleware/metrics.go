@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// CallStats summarizes the calls made to one method of an agent.
+type CallStats struct {
+	Calls  int
+	Errors int
+	// TotalLatency is the sum of the duration of every call.
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+}
+
+// MeanLatency returns the mean duration of a call, or 0 if there have been no
+// calls.
+func (s CallStats) MeanLatency() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Calls)
+}
+
+func (s *CallStats) record(start time.Time, err error) {
+	latency := time.Since(start)
+	s.Calls++
+	s.TotalLatency += latency
+	if latency > s.MaxLatency {
+		s.MaxLatency = latency
+	}
+	if err != nil {
+		s.Errors++
+	}
+}
+
+// MetricsSnapshot holds the CallStats of each method of an agent.
+type MetricsSnapshot struct {
+	RecommendAction CallStats
+	Transition      CallStats
+	Learn           CallStats
+}
+
+// Metrics counts the calls made to an agent and measures their latency.
+// A single Metrics may be shared by several agents, in which case their calls
+// are combined. Metrics is safe for concurrent use.
+type Metrics struct {
+	mu       sync.Mutex
+	snapshot MetricsSnapshot
+}
+
+// NewMetrics returns a reference to a new Metrics.
+func NewMetrics() *Metrics {
+	return new(Metrics)
+}
+
+// Snapshot returns the stats accumulated thus far.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.snapshot
+}
+
+// Middleware returns Middleware that accumulates stats about every call to
+// the agent.
+func (m *Metrics) Middleware() Middleware {
+	return func(next iface.Agenter) iface.Agenter {
+		w := wrap(next)
+		w.recommend = func(state iface.Stater) (iface.Actioner, error) {
+			start := time.Now()
+			action, err := next.RecommendAction(state)
+			m.mu.Lock()
+			m.snapshot.RecommendAction.record(start, err)
+			m.mu.Unlock()
+			return action, err
+		}
+		w.transition = func(state iface.Stater, action iface.Actioner) error {
+			start := time.Now()
+			err := next.Transition(state, action)
+			m.mu.Lock()
+			m.snapshot.Transition.record(start, err)
+			m.mu.Unlock()
+			return err
+		}
+		w.learn = func(previousState iface.Stater, actionTaken iface.Actioner, currentState iface.Stater, reward float64) {
+			start := time.Now()
+			next.Learn(previousState, actionTaken, currentState, reward)
+			m.mu.Lock()
+			m.snapshot.Learn.record(start, nil)
+			m.mu.Unlock()
+		}
+		return w
+	}
+}
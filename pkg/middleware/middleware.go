@@ -0,0 +1,77 @@
+package middleware
+
+import "github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+
+// Middleware wraps an agent to add behavior to it.
+type Middleware func(next iface.Agenter) iface.Agenter
+
+// Chain wraps agent with each middleware, such that the first middleware is
+// the outermost, and thus sees each call first.
+func Chain(agent iface.Agenter, middleware ...Middleware) iface.Agenter {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		agent = middleware[i](agent)
+	}
+	return agent
+}
+
+// Unwrapper is implemented by every agent returned by the middleware in this
+// package, so that the wrapped agent (and any methods it has beyond
+// iface.Agenter) can be reached.
+type Unwrapper interface {
+	Unwrap() iface.Agenter
+}
+
+// Unwrap returns the innermost agent beneath any middleware.
+func Unwrap(agent iface.Agenter) iface.Agenter {
+	for {
+		u, ok := agent.(Unwrapper)
+		if !ok {
+			return agent
+		}
+		agent = u.Unwrap()
+	}
+}
+
+// wrapped is an agent whose methods default to those of next, and may be
+// individually overridden.
+type wrapped struct {
+	next       iface.Agenter
+	recommend  func(state iface.Stater) (iface.Actioner, error)
+	transition func(state iface.Stater, action iface.Actioner) error
+	learn      func(previousState iface.Stater, actionTaken iface.Actioner, currentState iface.Stater, reward float64)
+}
+
+func wrap(next iface.Agenter) *wrapped {
+	return &wrapped{
+		next:       next,
+		recommend:  next.RecommendAction,
+		transition: next.Transition,
+		learn:      next.Learn,
+	}
+}
+
+func (w *wrapped) RecommendAction(state iface.Stater) (iface.Actioner, error) {
+	return w.recommend(state)
+}
+
+func (w *wrapped) Transition(state iface.Stater, action iface.Actioner) error {
+	return w.transition(state, action)
+}
+
+func (w *wrapped) Learn(previousState iface.Stater, actionTaken iface.Actioner, currentState iface.Stater, reward float64) {
+	w.learn(previousState, actionTaken, currentState, reward)
+}
+
+func (w *wrapped) Unwrap() iface.Agenter {
+	return w.next
+}
+
+// idOf returns the ID of a state or action, or "<nil>" if it is nil.
+func idOf(v interface{ ID() string }) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return v.ID()
+}
+
+var _ Unwrapper = (*wrapped)(nil)
@@ -0,0 +1,160 @@
+package middleware_test
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/middleware"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubAgent recommends a fixed action, and counts the transitions it learns
+// from.
+type stubAgent struct {
+	recommend iface.Actioner
+	err       error
+	learned   int
+}
+
+func (s *stubAgent) RecommendAction(iface.Stater) (iface.Actioner, error) {
+	return s.recommend, s.err
+}
+
+func (s *stubAgent) Transition(iface.Stater, iface.Actioner) error {
+	return nil
+}
+
+func (s *stubAgent) Learn(iface.Stater, iface.Actioner, iface.Stater, float64) {
+	s.learned++
+}
+
+func newFixture(mc *gomock.Controller) (x, y iface.Actioner, a, b iface.Stater) {
+	actionX := agent.NewMockActioner(mc)
+	actionX.EXPECT().ID().Return("X").AnyTimes()
+	actionY := agent.NewMockActioner(mc)
+	actionY.EXPECT().ID().Return("Y").AnyTimes()
+	stateA := agent.NewMockStater(mc)
+	stateA.EXPECT().ID().Return("A").AnyTimes()
+	stateA.EXPECT().PossibleActions().Return([]iface.Actioner{actionX}).AnyTimes()
+	stateB := agent.NewMockStater(mc)
+	stateB.EXPECT().ID().Return("B").AnyTimes()
+	return actionX, actionY, stateA, stateB
+}
+
+func Test_ChainOrderAndUnwrap(t *testing.T) {
+	order := []string{}
+	tag := func(name string) middleware.Middleware {
+		return middleware.Recording(middleware.RecorderFunc(func(middleware.Transition) {
+			order = append(order, name)
+		}))
+	}
+
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, b := newFixture(mc)
+
+	inner := &stubAgent{recommend: x}
+	wrapped := middleware.Chain(inner, tag("outer"), tag("inner"))
+	wrapped.Learn(a, x, b, 1)
+
+	// Recording records after the wrapped agent learns, so the innermost
+	// recorder records first.
+	assert.Equal(t, []string{"inner", "outer"}, order)
+	assert.Equal(t, 1, inner.learned)
+	assert.Equal(t, inner, middleware.Unwrap(wrapped))
+}
+
+func Test_Logging(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, b := newFixture(mc)
+
+	var buf bytes.Buffer
+	wrapped := middleware.Logging(log.New(&buf, "", 0))(&stubAgent{recommend: x})
+	_, _ = wrapped.RecommendAction(a)
+	_ = wrapped.Transition(a, x)
+	wrapped.Learn(a, x, b, 2)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.True(t, strings.HasPrefix(lines[0], "RecommendAction state=A action=X duration="), lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "Transition state=A action=X duration="), lines[1])
+		assert.True(t, strings.HasPrefix(lines[2], "Learn previous=A action=X current=B reward=2 duration="), lines[2])
+	}
+}
+
+func Test_Metrics(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, b := newFixture(mc)
+
+	metrics := middleware.NewMetrics()
+	stub := &stubAgent{recommend: x}
+	wrapped := metrics.Middleware()(stub)
+	_, _ = wrapped.RecommendAction(a)
+	stub.err = fmt.Errorf("failed")
+	_, _ = wrapped.RecommendAction(a)
+	wrapped.Learn(a, x, b, 1)
+
+	snapshot := metrics.Snapshot()
+	assert.Equal(t, 2, snapshot.RecommendAction.Calls)
+	assert.Equal(t, 1, snapshot.RecommendAction.Errors)
+	assert.Equal(t, 0, snapshot.Transition.Calls)
+	assert.Equal(t, 1, snapshot.Learn.Calls)
+	assert.True(t, snapshot.RecommendAction.MaxLatency >= snapshot.RecommendAction.MeanLatency())
+}
+
+func Test_Recording(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, b := newFixture(mc)
+
+	recorded := []middleware.Transition{}
+	wrapped := middleware.Recording(middleware.RecorderFunc(func(t middleware.Transition) {
+		recorded = append(recorded, t)
+	}))(&stubAgent{})
+	wrapped.Learn(nil, nil, a, 0)
+	wrapped.Learn(a, x, b, 3)
+
+	if assert.Len(t, recorded, 1) {
+		assert.False(t, recorded[0].Time.IsZero())
+		assert.Equal(t, "A", recorded[0].PreviousStateID)
+		assert.Equal(t, "X", recorded[0].ActionID)
+		assert.Equal(t, "B", recorded[0].CurrentStateID)
+		assert.Equal(t, 3.0, recorded[0].Reward)
+	}
+}
+
+func Test_Invariants(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, y, a, b := newFixture(mc)
+
+	violations := []error{}
+	stub := &stubAgent{recommend: y}
+	wrapped := middleware.Invariants(func(err error) {
+		violations = append(violations, err)
+	})(stub)
+
+	_, err := wrapped.RecommendAction(a)
+	assert.Error(t, err, "Y is not possible in A")
+
+	stub.recommend = x
+	action, err := wrapped.RecommendAction(a)
+	assert.NoError(t, err)
+	assert.Equal(t, x, action)
+
+	wrapped.Learn(a, x, nil, 1)
+	wrapped.Learn(a, x, b, math.NaN())
+	wrapped.Learn(a, x, b, 1)
+	wrapped.Learn(nil, nil, b, 0)
+	assert.Equal(t, 2, stub.learned)
+	assert.Len(t, violations, 3)
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Transition is a transition that an agent has learned from.
+type Transition struct {
+	Time            time.Time
+	PreviousStateID string
+	ActionID        string
+	CurrentStateID  string
+	Reward          float64
+}
+
+// Recorder receives the transitions captured by Recording.
+type Recorder interface {
+	Record(t Transition)
+}
+
+// RecorderFunc adapts an ordinary function to the Recorder interface.
+type RecorderFunc func(t Transition)
+
+// Record calls f(t).
+func (f RecorderFunc) Record(t Transition) {
+	f(t)
+}
+
+// Recording returns Middleware that captures every transition the agent
+// learns from. Calls to Learn without a previous state or action (which
+// agents treat as a no-op) are not recorded.
+func Recording(recorder Recorder) Middleware {
+	return func(next iface.Agenter) iface.Agenter {
+		w := wrap(next)
+		w.learn = func(previousState iface.Stater, actionTaken iface.Actioner, currentState iface.Stater, reward float64) {
+			next.Learn(previousState, actionTaken, currentState, reward)
+			if previousState == nil || actionTaken == nil {
+				return
+			}
+			recorder.Record(Transition{
+				Time:            time.Now(),
+				PreviousStateID: previousState.ID(),
+				ActionID:        actionTaken.ID(),
+				CurrentStateID:  idOf(currentState),
+				Reward:          reward,
+			})
+		}
+		return w
+	}
+}
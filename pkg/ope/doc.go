@@ -0,0 +1,4 @@
+// Package ope provides off-policy evaluation, which estimates the value a
+// target policy would have achieved from decisions logged while a different
+// (behavior) policy was in control.
+package ope
@@ -0,0 +1,283 @@
+package ope
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Record is a decision made by the behavior policy, along with the reward it
+// earned.
+type Record struct {
+	// DecisionID identifies the decision, if it was logged with an ID (as a
+	// qlearning.Decision is), so that its reward can be joined to it later.
+	DecisionID string `json:",omitempty"`
+	StateID    string
+	// State is the state for which the decision was made. It is only required
+	// by policies that inspect the state, such as AgentPolicy, and is not
+	// serialized.
+	State iface.Stater `json:"-"`
+	// Actions lists the IDs of the actions that were possible.
+	Actions []string `json:",omitempty"`
+	// ActionID is the ID of the action chosen by the behavior policy.
+	ActionID string
+	// Propensity is the probability with which the behavior policy chose the
+	// action.
+	Propensity float64
+	Reward     float64
+}
+
+// ReadRecords reads records from r, which must contain one JSON object per
+// line. Blank lines are ignored. Fields other than those of Record (such as
+// the time of a logged decision) are also ignored.
+//
+// A log of qlearning.Decision values can be read directly, but decisions carry
+// no reward, so every record read from such a log has a reward of zero. Use
+// JoinRewards to supply the reward each decision eventually earned.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	records := []Record{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// JoinRewards returns the records whose DecisionID has a reward in rewards,
+// with their Reward set accordingly, in their original order. Records without
+// a reward (whose outcome is not yet known, for example) are omitted, and
+// their number is returned as missing.
+func JoinRewards(records []Record, rewards map[string]float64) (joined []Record, missing int) {
+	joined = []Record{}
+	for _, record := range records {
+		reward, found := rewards[record.DecisionID]
+		if record.DecisionID == "" || !found {
+			missing++
+			continue
+		}
+		record.Reward = reward
+		joined = append(joined, record)
+	}
+	return joined, missing
+}
+
+// RewardModel estimates the reward the action with the specified ID would earn
+// for the state of a record.
+type RewardModel func(record Record, actionID string) float64
+
+// Config configures an evaluation.
+type Config struct {
+	// Policy is the target policy.
+	Policy Policy
+	// RewardModel is used by the doubly robust estimator. If nil, the mean
+	// reward logged for each state and action (or 0 if none was logged) is
+	// used.
+	RewardModel RewardModel
+	// Bootstrap is the number of bootstrap resamples used to estimate
+	// confidence intervals. Zero disables the intervals.
+	Bootstrap int
+	// Confidence is the coverage of the confidence intervals, such as 0.95.
+	Confidence float64
+	// Seed seeds the bootstrap resampling.
+	Seed int64
+}
+
+// Estimate is an estimate of the value of the target policy. If bootstrapping
+// is disabled, Low and High equal Value.
+type Estimate struct {
+	Value float64
+	Low   float64
+	High  float64
+}
+
+// Result holds the estimates of an evaluation.
+type Result struct {
+	Records int
+	// IPS is the inverse propensity scoring estimate, which is unbiased, but
+	// has high variance when the policies differ greatly.
+	IPS Estimate
+	// SNIPS is the self-normalized IPS estimate, which trades a little bias
+	// for much less variance.
+	SNIPS Estimate
+	// DR is the doubly robust estimate, which combines the reward model with
+	// IPS, and is unbiased if either the propensities or the model are
+	// correct.
+	DR Estimate
+	// EffectiveSampleSize is the number of records that the importance
+	// weights are worth. A value much lower than Records indicates that the
+	// estimates rely on few records.
+	EffectiveSampleSize float64
+}
+
+// sample holds the terms that each estimator needs from a record.
+type sample struct {
+	weight   float64
+	reward   float64
+	direct   float64
+	modelled float64
+}
+
+// Evaluate estimates the value of the target policy from records logged by
+// the behavior policy. An error is returned if there are no records, if any
+// propensity is not within (0, 1], or if the policy returns an error.
+func Evaluate(records []Record, c Config) (Result, error) {
+	if len(records) == 0 {
+		return Result{}, fmt.Errorf("at least one record is required")
+	}
+	if c.Policy == nil {
+		return Result{}, fmt.Errorf("a target policy is required")
+	}
+	if c.Bootstrap < 0 {
+		return Result{}, fmt.Errorf("bootstrap resamples must not be negative, got %v", c.Bootstrap)
+	}
+	if c.Bootstrap > 0 && !(c.Confidence > 0 && c.Confidence < 1) {
+		return Result{}, fmt.Errorf("confidence must be between 0 and 1 exclusive, got %v", c.Confidence)
+	}
+	model := c.RewardModel
+	if model == nil {
+		model = meanRewardModel(records)
+	}
+
+	samples := make([]sample, len(records))
+	for i, record := range records {
+		if !(record.Propensity > 0 && record.Propensity <= 1) {
+			return Result{}, fmt.Errorf("record %v has propensity %v, which is not within (0, 1]", i, record.Propensity)
+		}
+		distribution, err := c.Policy.Distribution(record)
+		if err != nil {
+			return Result{}, err
+		}
+		direct := 0.0
+		for actionID, p := range distribution {
+			direct += p * model(record, actionID)
+		}
+		samples[i] = sample{
+			weight:   distribution[record.ActionID] / record.Propensity,
+			reward:   record.Reward,
+			direct:   direct,
+			modelled: model(record, record.ActionID),
+		}
+	}
+
+	result := Result{
+		Records:             len(records),
+		IPS:                 pointEstimate(ips(samples)),
+		SNIPS:               pointEstimate(snips(samples)),
+		DR:                  pointEstimate(dr(samples)),
+		EffectiveSampleSize: effectiveSampleSize(samples),
+	}
+	if c.Bootstrap > 0 {
+		bootstrap(samples, c, &result)
+	}
+	return result, nil
+}
+
+func pointEstimate(value float64) Estimate {
+	return Estimate{Value: value, Low: value, High: value}
+}
+
+func ips(samples []sample) float64 {
+	sum := 0.0
+	for _, s := range samples {
+		sum += s.weight * s.reward
+	}
+	return sum / float64(len(samples))
+}
+
+func snips(samples []sample) float64 {
+	sum, weights := 0.0, 0.0
+	for _, s := range samples {
+		sum += s.weight * s.reward
+		weights += s.weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
+
+func dr(samples []sample) float64 {
+	sum := 0.0
+	for _, s := range samples {
+		sum += s.direct + s.weight*(s.reward-s.modelled)
+	}
+	return sum / float64(len(samples))
+}
+
+func effectiveSampleSize(samples []sample) float64 {
+	sum, squares := 0.0, 0.0
+	for _, s := range samples {
+		sum += s.weight
+		squares += s.weight * s.weight
+	}
+	if squares == 0 {
+		return 0
+	}
+	return sum * sum / squares
+}
+
+// bootstrap sets the interval of each estimate of result to the percentile
+// interval of the estimates of resampled records.
+func bootstrap(samples []sample, c Config, result *Result) {
+	rng := rand.New(rand.NewSource(c.Seed))
+	estimates := [3][]float64{}
+	resampled := make([]sample, len(samples))
+	for b := 0; b < c.Bootstrap; b++ {
+		for i := range resampled {
+			resampled[i] = samples[rng.Intn(len(samples))]
+		}
+		estimates[0] = append(estimates[0], ips(resampled))
+		estimates[1] = append(estimates[1], snips(resampled))
+		estimates[2] = append(estimates[2], dr(resampled))
+	}
+	tail := (1 - c.Confidence) / 2
+	for i, e := range []*Estimate{&result.IPS, &result.SNIPS, &result.DR} {
+		sort.Float64s(estimates[i])
+		e.Low = percentile(estimates[i], tail)
+		e.High = percentile(estimates[i], 1-tail)
+	}
+}
+
+// percentile returns the p-th percentile of sorted values, interpolating
+// between neighbouring values.
+func percentile(sorted []float64, p float64) float64 {
+	position := p * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	fraction := position - float64(lower)
+	return sorted[lower] + fraction*(sorted[upper]-sorted[lower])
+}
+
+// meanRewardModel returns a RewardModel that estimates the reward of an
+// action to be the mean reward logged for the same state and action.
+func meanRewardModel(records []Record) RewardModel {
+	type key struct{ stateID, actionID string }
+	sums := map[key]float64{}
+	counts := map[key]float64{}
+	for _, record := range records {
+		k := key{record.StateID, record.ActionID}
+		sums[k] += record.Reward
+		counts[k]++
+	}
+	return func(record Record, actionID string) float64 {
+		k := key{record.StateID, actionID}
+		if counts[k] == 0 {
+			return 0
+		}
+		return sums[k] / counts[k]
+	}
+}
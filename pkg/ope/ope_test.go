@@ -0,0 +1,187 @@
+package ope_test
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/ope"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// uniformLog returns records of a behavior policy that chooses uniformly
+// between actions A and B, where A earns a reward with probability .8 and B
+// with probability .2.
+func uniformLog(n int) []ope.Record {
+	rng := rand.New(rand.NewSource(1))
+	records := make([]ope.Record, n)
+	for i := range records {
+		action, rate := "A", .8
+		if rng.Intn(2) == 1 {
+			action, rate = "B", .2
+		}
+		reward := 0.0
+		if rng.Float64() < rate {
+			reward = 1
+		}
+		records[i] = ope.Record{
+			StateID:    "S",
+			Actions:    []string{"A", "B"},
+			ActionID:   action,
+			Propensity: .5,
+			Reward:     reward,
+		}
+	}
+	return records
+}
+
+func alwaysA(ope.Record) (map[string]float64, error) {
+	return map[string]float64{"A": 1}, nil
+}
+
+func Test_Evaluate(t *testing.T) {
+	result, err := ope.Evaluate(uniformLog(2000), ope.Config{
+		Policy:     ope.PolicyFunc(alwaysA),
+		Bootstrap:  200,
+		Confidence: .95,
+		Seed:       1,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 2000, result.Records)
+	for name, e := range map[string]ope.Estimate{"IPS": result.IPS, "SNIPS": result.SNIPS, "DR": result.DR} {
+		assert.InDelta(t, .8, e.Value, .05, name)
+		assert.True(t, e.Low < .8 && .8 < e.High, "%v interval [%v, %v]", name, e.Low, e.High)
+		assert.True(t, e.Low <= e.Value && e.Value <= e.High, name)
+	}
+	assert.InDelta(t, 1000, result.EffectiveSampleSize, 50)
+	// With an accurate reward model, DR has less variance than IPS.
+	assert.True(t, result.DR.High-result.DR.Low < result.IPS.High-result.IPS.Low)
+}
+
+func Test_EvaluateWithoutBootstrap(t *testing.T) {
+	records := []ope.Record{
+		{StateID: "S", ActionID: "A", Propensity: .5, Reward: 1},
+		{StateID: "S", ActionID: "B", Propensity: .5, Reward: 0},
+	}
+	result, err := ope.Evaluate(records, ope.Config{
+		Policy: ope.PolicyFunc(alwaysA),
+		RewardModel: func(ope.Record, string) float64 {
+			return .5
+		},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, ope.Estimate{Value: 1, Low: 1, High: 1}, result.IPS)
+		assert.Equal(t, ope.Estimate{Value: 1, Low: 1, High: 1}, result.SNIPS)
+		// (.5 + 2*(1-.5) + .5 + 0) / 2
+		assert.Equal(t, 1.0, result.DR.Value)
+	}
+}
+
+func Test_EvaluateInvalid(t *testing.T) {
+	policy := ope.PolicyFunc(alwaysA)
+	_, err := ope.Evaluate(nil, ope.Config{Policy: policy})
+	assert.Error(t, err)
+	_, err = ope.Evaluate(uniformLog(1), ope.Config{})
+	assert.Error(t, err)
+	_, err = ope.Evaluate([]ope.Record{{ActionID: "A"}}, ope.Config{Policy: policy})
+	assert.Error(t, err)
+	_, err = ope.Evaluate(uniformLog(1), ope.Config{Policy: policy, Bootstrap: 10})
+	assert.Error(t, err)
+}
+
+func Test_AgentPolicy(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	action := agent.NewMockActioner(mc)
+	action.EXPECT().ID().Return("A").AnyTimes()
+	state := agent.NewMockStater(mc)
+	state.EXPECT().ID().Return("S").AnyTimes()
+	state.EXPECT().PossibleActions().Return([]iface.Actioner{action}).AnyTimes()
+	state.EXPECT().GetAction("A").Return(action, nil).AnyTimes()
+
+	policy := ope.AgentPolicy(qlearning.NewBayesianAgent(1, .5, .5))
+	distribution, err := policy.Distribution(ope.Record{StateID: "S", State: state})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]float64{"A": 1}, distribution)
+	}
+
+	_, err = policy.Distribution(ope.Record{StateID: "S"})
+	assert.Error(t, err)
+}
+
+func Test_AgentPolicyRanksBayesianAgent(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	x := agent.NewMockActioner(mc)
+	x.EXPECT().ID().Return("X").AnyTimes()
+	y := agent.NewMockActioner(mc)
+	y.EXPECT().ID().Return("Y").AnyTimes()
+	state := agent.NewMockStater(mc)
+	state.EXPECT().ID().Return("S").AnyTimes()
+	state.EXPECT().PossibleActions().Return([]iface.Actioner{x, y}).AnyTimes()
+	terminal := agent.NewMockStater(mc)
+	terminal.EXPECT().ID().Return("T").AnyTimes()
+	terminal.EXPECT().PossibleActions().Return([]iface.Actioner{}).AnyTimes()
+
+	var log bytes.Buffer
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithExploration(qlearning.ConstantRate(.2)),
+		qlearning.WithDecisionLogger(qlearning.NewJSONDecisionLogger(&log)),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(state, y, terminal, 1)
+
+	// The distribution reflects exploration, and evaluating the agent does
+	// not make (and so does not log) any recommendation.
+	distribution, err := ope.AgentPolicy(ba).Distribution(ope.Record{StateID: "S", State: state})
+	if assert.NoError(t, err) {
+		assert.InDelta(t, .1, distribution["X"], 1e-9)
+		assert.InDelta(t, .9, distribution["Y"], 1e-9)
+	}
+	assert.Zero(t, log.Len())
+}
+
+func Test_JoinRewards(t *testing.T) {
+	records := []ope.Record{
+		{DecisionID: "1", StateID: "S", ActionID: "A", Propensity: .5},
+		{DecisionID: "2", StateID: "S", ActionID: "B", Propensity: .5},
+		{StateID: "S", ActionID: "B", Propensity: .5},
+	}
+	joined, missing := ope.JoinRewards(records, map[string]float64{"1": 3})
+	assert.Equal(t, []ope.Record{
+		{DecisionID: "1", StateID: "S", ActionID: "A", Propensity: .5, Reward: 3},
+	}, joined)
+	assert.Equal(t, 2, missing)
+}
+
+func Test_ReadRecords(t *testing.T) {
+	log := `{"DecisionID":"1","StateID":"S","Actions":["A","B"],"ActionID":"A","Propensity":0.5,"Reward":1}
+
+{"StateID":"S","ActionID":"B","Propensity":0.5,"Reward":0}
+`
+	records, err := ope.ReadRecords(strings.NewReader(log))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []ope.Record{
+			{DecisionID: "1", StateID: "S", Actions: []string{"A", "B"}, ActionID: "A", Propensity: .5, Reward: 1},
+			{StateID: "S", ActionID: "B", Propensity: .5},
+		}, records)
+	}
+
+	_, err = ope.ReadRecords(strings.NewReader("{"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package ope
+
+import (
+	"fmt"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// A Policy describes the target policy being evaluated.
+type Policy interface {
+	// Distribution returns the probability with which the policy would choose
+	// each action for the state of a record, keyed by action ID. Actions
+	// that are omitted have a probability of zero.
+	Distribution(record Record) (map[string]float64, error)
+}
+
+// PolicyFunc adapts an ordinary function to the Policy interface.
+type PolicyFunc func(record Record) (map[string]float64, error)
+
+// Distribution calls f(record).
+func (f PolicyFunc) Distribution(record Record) (map[string]float64, error) {
+	return f(record)
+}
+
+// ranker is implemented by agents that can report the probability with which
+// they would recommend each action, as qlearning.BayesianAgent does.
+type ranker interface {
+	RankActions(state iface.Stater) ([]qlearning.RankedAction, error)
+}
+
+// AgentPolicy returns a Policy that evaluates an agent. Each record must
+// provide its State.
+//
+// If the agent can rank its actions (as a qlearning.BayesianAgent can), the
+// distribution is the probability the agent reports for each action, and no
+// recommendation is made, so nothing is logged or attributed as a side effect.
+// Ranking does not modify the agent (see qlearning.BayesianAgent.RankActions),
+// although the fallback of the agent's qlearning.ConfidencePolicy, if any, is
+// called.
+// Otherwise, the agent is evaluated as though it were deterministic: it is
+// asked for one recommendation per record, and the recommended action has a
+// probability of 1, so an agent that explores or breaks ties at random is
+// evaluated on a single sample of its choices.
+func AgentPolicy(agent iface.Agenter) Policy {
+	return PolicyFunc(func(record Record) (map[string]float64, error) {
+		if record.State == nil {
+			return nil, fmt.Errorf("record for state '%v' does not provide its state", record.StateID)
+		}
+		if r, ok := agent.(ranker); ok {
+			ranked, err := r.RankActions(record.State)
+			if err != nil {
+				return nil, err
+			}
+			distribution := make(map[string]float64, len(ranked))
+			for _, action := range ranked {
+				if action.Probability > 0 {
+					distribution[action.ActionID] = action.Probability
+				}
+			}
+			return distribution, nil
+		}
+		action, err := agent.RecommendAction(record.State)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]float64{action.ID(): 1}, nil
+	})
+}
@@ -11,6 +11,17 @@ type ActionStats struct {
 	// QValueWeighted is the q-value for this action that has been weighted
 	// according to the agent's weighting rules.
 	QWeighted float64
+	// Rewards summarizes the rewards observed for this action.
+	Rewards RunningStats
+	// Targets summarizes the temporal difference targets (reward plus
+	// discounted future value) toward which the raw q-value has been updated.
+	Targets RunningStats
+	// Decay is the effective number of calls, which is only maintained when
+	// the agent forgets (see Forgetting).
+	Decay *Evidence `json:",omitempty"`
+	// Recent summarizes the most recent rewards, which are only maintained
+	// when the agent forgets with a window (see Forgetting).
+	Recent *WindowStats `json:",omitempty"`
 }
 
 // Calls returns the number of times this action has been called.
@@ -43,4 +54,78 @@ func (as *ActionStats) SetQValueWeighted(value float64) {
 	as.QWeighted = value
 }
 
-var _ iface.ActionStatter = (*ActionStats)(nil)
+// Observe records a reward observed for this action, and the temporal
+// difference target derived from it.
+func (as *ActionStats) Observe(reward, target float64) {
+	as.Rewards.Add(reward)
+	as.Targets.Add(target)
+}
+
+// Variance returns the sample variance of the targets observed for this
+// action.
+func (as *ActionStats) Variance() float64 {
+	return as.Targets.Variance()
+}
+
+// RewardStats returns a summary of the rewards observed for this action.
+func (as *ActionStats) RewardStats() RunningStats {
+	return as.Rewards
+}
+
+// TargetStats returns a summary of the targets observed for this action.
+func (as *ActionStats) TargetStats() RunningStats {
+	return as.Targets
+}
+
+// Evidence returns the effective number of times this action has been called.
+func (as *ActionStats) Evidence() Evidence {
+	if as.Decay == nil {
+		return Evidence{}
+	}
+	return *as.Decay
+}
+
+// SetEvidence sets the effective number of times this action has been called.
+func (as *ActionStats) SetEvidence(e Evidence) {
+	as.Decay = &e
+}
+
+// ObserveRecent records a reward in a window of the most recent size rewards.
+func (as *ActionStats) ObserveRecent(reward float64, size int) {
+	if as.Recent == nil {
+		as.Recent = new(WindowStats)
+	}
+	as.Recent.Add(reward, size)
+}
+
+// RecentRewards returns a summary of the most recent rewards observed for this
+// action, or the zero value if no window is maintained.
+func (as *ActionStats) RecentRewards() RunningStats {
+	if as.Recent == nil {
+		return RunningStats{}
+	}
+	return as.Recent.Stats()
+}
+
+// Reset forgets every observation of this action, retaining only its raw and
+// weighted q-values as the starting point for future updates.
+func (as *ActionStats) Reset() {
+	*as = ActionStats{QRaw: as.QRaw, QWeighted: as.QWeighted}
+}
+
+// ObservationStatter is an optional interface that an ActionStatter may
+// implement to summarize the rewards and targets it has observed. It is
+// required by BayesianAgent.ConfidenceInterval.
+type ObservationStatter interface {
+	RewardStats() RunningStats
+	TargetStats() RunningStats
+}
+
+var (
+	_ iface.ActionStatter   = (*ActionStats)(nil)
+	_ iface.Observer        = (*ActionStats)(nil)
+	_ iface.VarianceStatter = (*ActionStats)(nil)
+	_ ObservationStatter    = (*ActionStats)(nil)
+	_ Forgetter             = (*ActionStats)(nil)
+	_ Resetter              = (*ActionStats)(nil)
+)
@@ -0,0 +1,226 @@
+package qlearning
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// ErrDecisionNotPending is returned (wrapped) when a decision ID is not
+// awaiting a transition or reward, either because it was never issued, or
+// because it has already been learned from or has expired. Use errors.Is to
+// detect it.
+var ErrDecisionNotPending = errors.New("decision is not pending")
+
+// Attribution configures delayed reward attribution. See WithAttribution.
+type Attribution struct {
+	// Window is how long a decision awaits its reward. Must be positive.
+	Window time.Duration
+	// DefaultReward is learned from when a decision's window elapses without
+	// a reward, provided that its transition has been recorded.
+	DefaultReward float64
+	// OnExpire, if not nil, is called with the ID of each decision whose
+	// window elapses without a reward. learned is false if the decision's
+	// transition was never recorded, in which case nothing can be learned.
+	OnExpire func(decisionID string, learned bool)
+}
+
+// pendingDecision is a decision awaiting its transition and reward.
+type pendingDecision struct {
+	issued    time.Time
+	state     iface.Stater
+	action    iface.Actioner
+	nextState iface.Stater
+	reward    *float64
+}
+
+// WithAttribution enables delayed reward attribution, for systems in which
+// the reward for an action arrives long after the next state is observed.
+// Each decision issued by RecommendDecision becomes pending. The agent learns
+// from a pending decision once both its transition (see RecordTransition) and
+// its reward (see Reward) have been supplied, in either order. If the reward
+// does not arrive within the attribution window, the agent learns from the
+// default reward instead. Pending decisions are not persisted with the
+// agent's context. Windows are measured by the agent's clock (see WithClock).
+func WithAttribution(attribution Attribution) Option {
+	return func(a *BayesianAgent) error {
+		if attribution.Window <= 0 {
+			return fmt.Errorf("attribution window must be positive, got %v", attribution.Window)
+		}
+		a.attribution = &attribution
+		a.pending = make(map[string]*pendingDecision)
+		return nil
+	}
+}
+
+// track makes a decision pending, if attribution is enabled.
+func (a *BayesianAgent) track(decision Decision, state iface.Stater, action iface.Actioner) {
+	if a.attribution == nil {
+		return
+	}
+	a.ExpireDecisions()
+	a.pending[decision.DecisionID] = &pendingDecision{
+		issued: decision.Time,
+		state:  freeze(state, 0),
+		action: action,
+	}
+}
+
+// RecordTransition records the state that resulted from the action of a
+// pending decision. If the decision's reward has already been supplied, the
+// agent learns from the decision. An error wrapping ErrDecisionNotPending is
+// returned if the decision is not pending.
+//
+// The ID, possible actions, and ancestors (see iface.Parenter) of the state of
+// each pending decision, and of the state recorded here, are captured when
+// the decision is issued and when the transition is recorded, respectively.
+// The agent thus learns from the states as they were at those times, even if
+// the caller later modifies them in place.
+func (a *BayesianAgent) RecordTransition(decisionID string, currentState iface.Stater) error {
+	if currentState == nil {
+		return fmt.Errorf("currentState must not be nil")
+	}
+	p, err := a.pendingDecision(decisionID)
+	if err != nil {
+		return err
+	}
+	p.nextState = freeze(currentState, 0)
+	if p.reward != nil {
+		a.settle(decisionID, *p.reward)
+	}
+	return nil
+}
+
+// Reward supplies the reward for a pending decision. If the decision's
+// transition has already been recorded, the agent learns from the decision.
+// An error wrapping ErrDecisionNotPending is returned if the decision is not
+// pending.
+func (a *BayesianAgent) Reward(decisionID string, value float64) error {
+	p, err := a.pendingDecision(decisionID)
+	if err != nil {
+		return err
+	}
+	p.reward = &value
+	if p.nextState != nil {
+		a.settle(decisionID, value)
+	}
+	return nil
+}
+
+// PendingDecisions returns the number of decisions awaiting a transition or
+// reward.
+func (a *BayesianAgent) PendingDecisions() int {
+	return len(a.pending)
+}
+
+// ExpireDecisions ends every pending decision whose attribution window has
+// elapsed, learning from the default reward for those whose transition has
+// been recorded, and returns the number of decisions expired. It is called
+// whenever a decision is issued, rewarded, or transitioned, so need only be
+// called explicitly to release decisions sooner.
+func (a *BayesianAgent) ExpireDecisions() int {
+	if a.attribution == nil {
+		return 0
+	}
+	deadline := a.clock().Add(-a.attribution.Window)
+	expired := []string{}
+	for id, p := range a.pending {
+		if p.issued.Before(deadline) {
+			expired = append(expired, id)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return a.pending[expired[i]].issued.Before(a.pending[expired[j]].issued)
+	})
+
+	for _, id := range expired {
+		p := a.pending[id]
+		learned := p.nextState != nil
+		if learned {
+			a.settle(id, a.attribution.DefaultReward)
+		} else {
+			delete(a.pending, id)
+		}
+		if a.attribution.OnExpire != nil {
+			a.attribution.OnExpire(id, learned)
+		}
+	}
+	return len(expired)
+}
+
+func (a *BayesianAgent) pendingDecision(decisionID string) (*pendingDecision, error) {
+	a.ExpireDecisions()
+	p, found := a.pending[decisionID]
+	if !found {
+		return nil, fmt.Errorf("decision '%v': %w", decisionID, ErrDecisionNotPending)
+	}
+	return p, nil
+}
+
+// settle learns from a pending decision, and ends it.
+func (a *BayesianAgent) settle(decisionID string, reward float64) {
+	p := a.pending[decisionID]
+	delete(a.pending, decisionID)
+	a.Learn(p.state, p.action, p.nextState, reward)
+}
+
+// frozenState captures the parts of a state that Learn relies upon, so that
+// a pending decision is unaffected by later changes to the caller's state.
+type frozenState struct {
+	id      string
+	actions []iface.Actioner
+	parent  iface.Stater
+}
+
+// freeze returns a copy of a state's ID, possible actions, and ancestors.
+func freeze(state iface.Stater, depth int) iface.Stater {
+	f := &frozenState{
+		id:      state.ID(),
+		actions: append([]iface.Actioner{}, state.PossibleActions()...),
+	}
+	if parent := parentOf(state); parent != nil && depth < maxHierarchyDepth {
+		f.parent = freeze(parent, depth+1)
+	}
+	return f
+}
+
+func (s *frozenState) PossibleActions() []iface.Actioner {
+	return s.actions
+}
+
+func (s *frozenState) ActionIsCompatible(action iface.Actioner) bool {
+	_, err := s.GetAction(action.ID())
+	return err == nil
+}
+
+func (s *frozenState) GetAction(id string) (iface.Actioner, error) {
+	for _, action := range s.actions {
+		if action.ID() == id {
+			return action, nil
+		}
+	}
+	return nil, fmt.Errorf("action '%v' is not possible in state '%v'", id, s.id)
+}
+
+func (s *frozenState) ID() string {
+	return s.id
+}
+
+func (s *frozenState) Apply(iface.Actioner) error {
+	return fmt.Errorf("state '%v' is a snapshot and can not be applied", s.id)
+}
+
+func (s *frozenState) Parent() iface.Stater {
+	if s.parent == nil {
+		return nil
+	}
+	return s.parent
+}
+
+var (
+	_ iface.Stater   = (*frozenState)(nil)
+	_ iface.Parenter = (*frozenState)(nil)
+)
@@ -0,0 +1,116 @@
+package qlearning_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Attribution(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	_, state, terminal := newRecommendFixture(mc, "X")
+
+	now := time.Unix(0, 0)
+	expired := map[string]bool{}
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithClock(func() time.Time { return now }),
+		qlearning.WithAttribution(qlearning.Attribution{
+			Window:        time.Hour,
+			DefaultReward: -1,
+			OnExpire:      func(id string, learned bool) { expired[id] = learned },
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	rawX := func() float64 {
+		stats, found := ba.GetAgentContext().QValues["A"]["X"]
+		if !found {
+			return 0
+		}
+		return stats.QValueRaw()
+	}
+
+	// Transition, then reward.
+	_, first, err := ba.RecommendDecision(state)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, ba.RecordTransition(first.DecisionID, terminal))
+	assert.Equal(t, 0.0, rawX(), "nothing is learned until the reward arrives")
+	assert.NoError(t, ba.Reward(first.DecisionID, 5))
+	assert.Equal(t, 5.0, rawX())
+	assert.Equal(t, 0, ba.PendingDecisions())
+
+	err = ba.Reward(first.DecisionID, 5)
+	assert.True(t, errors.Is(err, qlearning.ErrDecisionNotPending), "unexpected error %v", err)
+
+	// Reward, then transition.
+	_, second, _ := ba.RecommendDecision(state)
+	assert.NoError(t, ba.Reward(second.DecisionID, 3))
+	assert.NoError(t, ba.RecordTransition(second.DecisionID, terminal))
+	assert.Equal(t, 3.0, rawX())
+
+	// Expiry with and without a transition.
+	_, third, _ := ba.RecommendDecision(state)
+	_, fourth, _ := ba.RecommendDecision(state)
+	assert.NoError(t, ba.RecordTransition(third.DecisionID, terminal))
+	assert.Equal(t, 2, ba.PendingDecisions())
+
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, 2, ba.ExpireDecisions())
+	assert.Equal(t, map[string]bool{third.DecisionID: true, fourth.DecisionID: false}, expired)
+	assert.Equal(t, -1.0, rawX())
+	assert.Equal(t, 0, ba.PendingDecisions())
+
+	err = ba.Reward(fourth.DecisionID, 1)
+	assert.True(t, errors.Is(err, qlearning.ErrDecisionNotPending))
+}
+
+func Test_WithAttributionInvalid(t *testing.T) {
+	_, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithAttribution(qlearning.Attribution{}))
+	assert.Error(t, err)
+}
+
+func Test_AttributionCapturesStates(t *testing.T) {
+	now := time.Unix(1000, 0)
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(1),
+		qlearning.WithClock(func() time.Time { return now }),
+		qlearning.WithAttribution(qlearning.Attribution{Window: time.Hour}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	state := &treeState{id: "A", actions: []iface.Actioner{testAction("X")}}
+	_, decision, err := ba.RecommendDecision(state)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, now, decision.Time)
+
+	// The caller modifies the same state object in place before the
+	// transition and reward arrive.
+	state.id = "B"
+	assert.NoError(t, ba.RecordTransition(decision.DecisionID, state))
+	state.id = "C"
+	state.actions = nil
+	assert.NoError(t, ba.Reward(decision.DecisionID, 2))
+
+	c := ba.GetAgentContext()
+	if assert.Contains(t, c.QValues, "A") {
+		assert.Equal(t, 2.0, c.QValues["A"]["X"].QValueRaw())
+	}
+	assert.Contains(t, c.QValues, "B")
+	assert.NotContains(t, c.QValues, "C")
+}
@@ -1,11 +1,14 @@
 package qlearning
 
 import (
+	"encoding"
 	"fmt"
 	"math"
 	"math/rand"
+	"sort"
 	"time"
 
+	"github.com/eltorocorp/reinforcement-learning/pkg/drift"
 	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
 	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/internal/datastructures"
 	qlmath "github.com/eltorocorp/reinforcement-learning/pkg/qlearning/internal/math"
@@ -31,12 +34,40 @@ import (
 // mean of all other actions. However, as an action is called more times, the
 // agent begins to evaluate the action on its observed cumulative reward moreso
 // than the mean of all other actions.
+//
+// The mean of all other actions is the default prior estimate of an action's
+// value. Other estimates, such as a fixed value or the action's value across
+// all states, can be supplied via WithPrior. Likewise, the Bayesian Average is
+// the default means of weighting, and alternatives can be supplied via
+// WithWeighter.
+//
+// Every random choice made by the agent (such as breaking ties between
+// actions of equal value) draws from a rand.Source owned by the agent. The
+// global math/rand source is never used or reseeded. See SetSeed and
+// SetRandSource.
 type BayesianAgent struct {
-	TieBreaker       func(int) int
-	qmap             *datastructures.QMap
-	learningRate     float64
-	discountFactor   float64
-	primingThreshold int
+	TieBreaker         func(int) int
+	source             rand.Source
+	rng                *rand.Rand
+	store              iface.QStorer
+	prior              Prior
+	weighter           Weighter
+	hierarchical       bool
+	hierarchyThreshold float64
+	learningRate       Schedule
+	exploration        *Schedule
+	confidence         *ConfidencePolicy
+	forgetting         *Forgetting
+	drift              *DriftMonitor
+	logger             DecisionLogger
+	attribution        *Attribution
+	pending            map[string]*pendingDecision
+	updateHook         func(Update)
+	clock              func() time.Time
+	detectors          map[string]map[string]drift.Detector
+	steps              int
+	discountFactor     float64
+	primingThreshold   int
 }
 
 // NewBayesianAgent returns a reference to a new BayesianAgent.
@@ -56,17 +87,44 @@ type BayesianAgent struct {
 //  From wikipedia: The discount factor determines the importance of future
 //  rewards.
 //  see: https://en.wikipedia.org/wiki/Q-learning#Discount_factor
+//
+// The agent's random source is seeded from the current time. Use SetSeed to
+// make the agent's behavior reproducible.
+//
+// NewBayesianAgent does not validate its arguments. NewBayesianAgentWithOptions
+// provides a validated alternative.
 func NewBayesianAgent(primingThreshold int, learningRate, discountFactor float64) *BayesianAgent {
-	return &BayesianAgent{
-		TieBreaker: func(n int) int {
-			rand.Seed(time.Now().Local().UnixNano())
-			return rand.Intn(n)
-		},
-		qmap:             datastructures.NewQMap(),
+	a := &BayesianAgent{
+		store:            datastructures.NewQMap(),
+		prior:            SiblingMeanPrior(),
+		weighter:         BayesianAverageWeighter(),
 		discountFactor:   discountFactor,
-		learningRate:     learningRate,
+		learningRate:     ConstantRate(learningRate),
 		primingThreshold: primingThreshold,
+		clock:            time.Now,
 	}
+	a.TieBreaker = func(n int) int {
+		return a.rng.Intn(n)
+	}
+	a.SetRandSource(NewSource(time.Now().UnixNano()))
+	return a
+}
+
+// SetSeed replaces the agent's random source with a new Source initialized
+// with seed. Two agents with the same seed, configuration, and history will
+// make identical random choices.
+func (a *BayesianAgent) SetSeed(seed int64) {
+	a.SetRandSource(NewSource(seed))
+}
+
+// SetRandSource replaces the agent's random source. If the source implements
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler (as Source does),
+// its state is captured by GetAgentContext and restored by SetAgentContext
+// (or their error-returning counterparts, CaptureAgentContext and
+// RestoreAgentContext).
+func (a *BayesianAgent) SetRandSource(source rand.Source) {
+	a.source = source
+	a.rng = rand.New(source)
 }
 
 // Learn updates the reinforcement model according to a transition that has
@@ -85,26 +143,150 @@ func (a *BayesianAgent) Learn(previousState iface.Stater, actionTaken iface.Acti
 	if currentState == nil {
 		panic("currentState must not be nil")
 	}
+	if d, ok := a.store.(EvictionDeferrer); ok {
+		d.DeferEviction()
+		defer d.ResumeEviction()
+	}
 
 	var stats iface.ActionStatter
 	var found bool
-	stats, found = a.qmap.GetStats(previousState, actionTaken)
+	stats, found = a.store.GetStats(previousState, actionTaken)
 	if !found {
 		stats = new(ActionStats)
 	}
 
+	now := a.now()
 	a.applyActionWeights(currentState)
-	newValue := qlmath.Bellman(
-		stats.QValueWeighted(),
-		a.learningRate,
+	bestValue := a.getBestValue(currentState)
+	base := a.updateBase(previousState, actionTaken.ID(), stats, a.store.GetActionsForState(previousState), now)
+	if a.hierarchical {
+		a.poolIntoAncestors(previousState, actionTaken, reward, bestValue, now)
+	}
+	newValue, target, tdError := a.updateStats(previousState, actionTaken, stats, base, reward, bestValue, now)
+	if a.updateHook != nil {
+		a.updateHook(Update{
+			StateID:   previousState.ID(),
+			ActionID:  actionTaken.ID(),
+			Step:      a.steps,
+			Reward:    reward,
+			Target:    target,
+			TDError:   tdError,
+			QValueRaw: newValue,
+		})
+	}
+	a.store.UpdateStats(previousState, actionTaken, stats)
+	a.observeStats(previousState.ID(), actionTaken.ID(), stats)
+	a.applyActionWeights(previousState)
+	a.steps++
+}
+
+// updateStats moves the raw q-value of an action from base toward the reward
+// plus the discounted bestValue, at the learning rate given by the action's
+// effective calls (see Forgetting), and records the call with the action's
+// evidence and drift detector. The caller is responsible for saving stats to
+// the store. The new raw q-value, the target, and the TD error are returned.
+func (a *BayesianAgent) updateStats(state iface.Stater, action iface.Actioner, stats iface.ActionStatter, base, reward, bestValue float64, now time.Time) (newValue, target, tdError float64) {
+	visits := stats.Calls()
+	if a.forgetting != nil {
+		visits = int(math.Round(a.effectiveCalls(stats, now)))
+	}
+	newValue = qlmath.Bellman(
+		base,
+		a.learningRate.RateAt(a.steps, visits+1),
 		reward,
 		a.discountFactor,
-		a.getBestValue(currentState),
+		bestValue,
 	)
+	target = reward + a.discountFactor*bestValue
+	tdError = target - base
+	observe(stats, reward, target)
+	a.remember(stats, reward, now)
 	stats.SetCalls(stats.Calls() + 1)
 	stats.SetQValueRaw(newValue)
-	a.qmap.UpdateStats(previousState, actionTaken, stats)
-	a.applyActionWeights(previousState)
+	a.monitorDrift(state, action, stats, reward, tdError)
+	return newValue, target, tdError
+}
+
+// observeStats informs the agent's prior of a change to the stats of an
+// action, if the prior is a StatsObserver. nil stats indicate that the
+// action's stats have been removed.
+func (a *BayesianAgent) observeStats(stateID, actionID string, stats iface.ActionStatter) {
+	if o, ok := a.prior.(StatsObserver); ok {
+		o.ObserveStats(stateID, actionID, stats)
+	}
+}
+
+// observeData informs the agent's prior of the stats of every action in data,
+// or forgets every action in data if removed is true.
+func (a *BayesianAgent) observeData(data map[string]map[string]iface.ActionStatter, removed bool) {
+	for stateID, actions := range data {
+		if removed {
+			a.forgetState(stateID, actions)
+			continue
+		}
+		for actionID, stats := range actions {
+			a.observeStats(stateID, actionID, stats)
+		}
+	}
+}
+
+// forget discards what the agent holds about an action of a state besides its
+// stats, once the stats have been removed from the store: the action's drift
+// detector, and its contribution to the prior.
+func (a *BayesianAgent) forget(stateID, actionID string) {
+	if detectors, found := a.detectors[stateID]; found {
+		delete(detectors, actionID)
+		if len(detectors) == 0 {
+			delete(a.detectors, stateID)
+		}
+	}
+	a.observeStats(stateID, actionID, nil)
+}
+
+// forgetState forgets every action of a state. It is registered with stores
+// that are EvictionNotifiers, so that evicted states are forgotten.
+func (a *BayesianAgent) forgetState(stateID string, actions map[string]iface.ActionStatter) {
+	for actionID := range actions {
+		a.forget(stateID, actionID)
+	}
+}
+
+// AddEvictListener registers a function to be called with each state as it is
+// evicted from the agent's store, if the store is an EvictionNotifier. If the
+// store does not evict states, the listener is never called.
+func (a *BayesianAgent) AddEvictListener(listener EvictFunc) {
+	if n, ok := a.store.(EvictionNotifier); ok {
+		n.AddEvictListener(listener)
+	}
+}
+
+// observe records a reward and target with stats, if the stats support it.
+func observe(stats iface.ActionStatter, reward, target float64) {
+	if o, ok := stats.(iface.Observer); ok {
+		o.Observe(reward, target)
+	}
+}
+
+// ConfidenceInterval returns the interval within which the mean temporal
+// difference target of an action (the value its raw q-value estimates) lies,
+// with the confidence implied by z, the number of standard errors either side
+// of the mean (1.96 for 95%). An error is returned if the action has not been
+// recorded for the state, if its stats do not implement ObservationStatter, or
+// if it has been observed fewer than two times.
+func (a *BayesianAgent) ConfidenceInterval(state iface.Stater, action iface.Actioner, z float64) (Interval, error) {
+	stats, found := a.store.GetStats(state, action)
+	if !found {
+		return Interval{}, fmt.Errorf("action '%v' has not been recorded for state '%v'", action.ID(), state.ID())
+	}
+	observed, ok := stats.(ObservationStatter)
+	if !ok {
+		return Interval{}, fmt.Errorf("stats for action '%v' of state '%v' do not record observations", action.ID(), state.ID())
+	}
+	targets := observed.TargetStats()
+	if targets.Count < 2 {
+		return Interval{}, fmt.Errorf("action '%v' of state '%v' has been observed %v times, at least 2 are required", action.ID(), state.ID(), targets.Count)
+	}
+	return targets.ConfidenceInterval(z), nil
 }
 
 // Transition applies an action to a given state.
@@ -116,64 +298,267 @@ func (a *BayesianAgent) Transition(currentState iface.Stater, action iface.Actio
 }
 
 // RecommendAction recommends an action for a given state based on behavior of
-// the system that the agent has learned thus far.
+// the system that the agent has learned thus far. Only the state's current
+// possible actions are considered, even if other actions have been recorded
+// for the state in the past.
 // If the q-value for two or more actions are the same, the action is chosen at
-// random. See BayesianAgent struct docs for more information.
+// random. If the agent has been configured to explore (see WithExploration),
+// a random action is instead recommended with probability equal to the
+// current exploration rate. If the agent has a ConfidencePolicy (see
+// WithConfidencePolicy), the best action is only recommended if the policy is
+// satisfied. If the agent has a DecisionLogger (see WithDecisionLogger), the
+// recommendation is logged. See BayesianAgent struct docs for more
+// information.
 func (a *BayesianAgent) RecommendAction(state iface.Stater) (iface.Actioner, error) {
-	type actionValue struct {
-		action string
-		value  float64
+	return a.RecommendActionWithMask(state, nil)
+}
+
+// ActionMask reports whether an action may be recommended.
+type ActionMask func(action iface.Actioner) bool
+
+// RecommendActionWithMask recommends an action for a given state, as
+// RecommendAction does, but only considers those of the state's possible
+// actions that are permitted by allowed. A nil mask permits every action.
+// An error is returned if no action is permitted.
+func (a *BayesianAgent) RecommendActionWithMask(state iface.Stater, allowed ActionMask) (iface.Actioner, error) {
+	action, _, err := a.recommend(state, allowed, a.logger != nil)
+	return action, err
+}
+
+// recommend chooses an action for a state from those permitted by allowed,
+// and applies the agent's ConfidencePolicy. If describe is true, recommend
+// also returns the Decision that describes the recommendation, and logs it if
+// the agent has a DecisionLogger. Otherwise, the Decision is empty.
+func (a *BayesianAgent) recommend(state iface.Stater, allowed ActionMask, describe bool) (iface.Actioner, Decision, error) {
+	d, err := a.decide(state, allowed)
+	if err != nil {
+		return nil, Decision{}, err
 	}
 
-	bestActions := []actionValue{}
-	bestValue := -1 * math.MaxFloat64
+	var action iface.Actioner
+	if a.confidence != nil && !d.explored {
+		if err := a.confidence.check(state, d, d.chosen); err != nil {
+			if a.confidence.Fallback == nil {
+				return nil, Decision{}, err
+			}
+			action, err = a.confidence.Fallback(state)
+			if err != nil {
+				return nil, Decision{}, err
+			}
+		}
+	}
+	if action == nil {
+		action, err = state.GetAction(d.chosen)
+		if err != nil {
+			return nil, Decision{}, err
+		}
+	}
+	if !describe {
+		return action, Decision{}, nil
+	}
+	probabilities, err := a.probabilities(state, d)
+	if err != nil {
+		return nil, Decision{}, err
+	}
 
-	a.applyActionWeights(state)
-	for action, stats := range a.qmap.GetActionsForState(state) {
-		av := actionValue{action, stats.QValueWeighted()}
-		if av.value > bestValue {
-			bestActions = []actionValue{av}
-			bestValue = av.value
-		} else if av.value == bestValue {
-			bestActions = append(bestActions, av)
+	decision, err := newDecision(a.clock(), state.ID(), d.actionIDs, action.ID(), probabilities[action.ID()])
+	if err != nil {
+		return nil, Decision{}, err
+	}
+	if a.logger != nil {
+		if err := a.logger.LogDecision(decision); err != nil {
+			return nil, Decision{}, fmt.Errorf("failed to log decision: %v", err)
 		}
 	}
+	return action, decision, nil
+}
+
+// decision records how the agent chose an action for a state.
+type decision struct {
+	actions   map[string]iface.ActionStatter
+	actionIDs []string
+	// calls holds the effective number of calls of each action (see
+	// Forgetting).
+	calls    map[string]float64
+	priors   map[string]float64
+	weighted map[string]float64
+	best     []string
+	rate     float64
+	explored bool
+	tieBreak int
+	chosen   string
+}
 
-	if len(bestActions) == 0 {
-		return nil, fmt.Errorf("state '%v' reports no possible actions", state.ID())
+// consider weighs the actions of a state that are permitted by allowed,
+// without choosing between them. Considering a state does not modify the
+// agent's store.
+func (a *BayesianAgent) consider(state iface.Stater, allowed ActionMask) (decision, error) {
+	d := decision{}
+	d.priors, d.actions, d.weighted = a.weigh(state)
+	d.actionIDs = candidates(state, nil)
+	if len(d.actionIDs) == 0 {
+		return d, fmt.Errorf("state '%v' reports no possible actions", state.ID())
+	}
+	if allowed != nil {
+		d.actionIDs = candidates(state, allowed)
+		if len(d.actionIDs) == 0 {
+			return d, fmt.Errorf("state '%v' has no possible actions permitted by the mask", state.ID())
+		}
+	}
+	d.best = bestOf(d.weighted, d.actionIDs)
+	d.rate = a.ExplorationRate()
+	now := a.now()
+	d.calls = make(map[string]float64, len(d.actionIDs))
+	for _, actionID := range d.actionIDs {
+		d.calls[actionID] = a.effectiveCalls(d.actions[actionID], now)
 	}
+	return d, nil
+}
 
-	tieBreaker := a.TieBreaker(len(bestActions))
-	bestAction, err := state.GetAction(bestActions[tieBreaker].action)
+// decide chooses an action for a state from those permitted by allowed,
+// consuming randomness exactly as RecommendAction does.
+func (a *BayesianAgent) decide(state iface.Stater, allowed ActionMask) (decision, error) {
+	d, err := a.consider(state, allowed)
 	if err != nil {
-		return nil, err
+		return d, err
+	}
+	if d.rate > 0 && a.rng.Float64() < d.rate {
+		d.explored = true
+		d.chosen = d.actionIDs[a.rng.Intn(len(d.actionIDs))]
+	} else {
+		d.tieBreak = a.TieBreaker(len(d.best))
+		d.chosen = d.best[d.tieBreak]
+	}
+	return d, nil
+}
+
+// probabilities returns the probability with which each action is
+// recommended for a state, keyed by action ID, assuming the tie breaker
+// chooses uniformly between tied actions. Each action is explored with
+// probability rate / |actions|. Otherwise, each of the tied best actions is
+// chosen with equal probability, and that share goes to the best action if it
+// satisfies the agent's ConfidencePolicy, or else to the policy's fallback
+// action. If the agent abstains when unconfident, the probabilities sum to
+// less than 1.
+func (a *BayesianAgent) probabilities(state iface.Stater, d decision) (map[string]float64, error) {
+	p := make(map[string]float64, len(d.actionIDs))
+	for _, actionID := range d.actionIDs {
+		p[actionID] = d.rate / float64(len(d.actionIDs))
+	}
+	share := (1 - d.rate) / float64(len(d.best))
+	fallback := ""
+	for _, best := range d.best {
+		if a.confidence == nil || a.confidence.check(state, d, best) == nil {
+			p[best] += share
+			continue
+		}
+		if a.confidence.Fallback == nil {
+			continue
+		}
+		if fallback == "" {
+			action, err := a.confidence.Fallback(state)
+			if err != nil {
+				return nil, err
+			}
+			fallback = action.ID()
+		}
+		p[fallback] += share
 	}
-	return bestAction, nil
+	return p, nil
 }
 
+// ExplorationRate returns the probability that the agent will currently
+// recommend a random action rather than the best known action. The rate is
+// zero unless the agent has been configured to explore (see WithExploration).
+func (a *BayesianAgent) ExplorationRate() float64 {
+	if a.exploration == nil {
+		return 0
+	}
+	return a.exploration.Rate(a.steps)
+}
+
+// bestOf returns the IDs of the actions that share the greatest weighted
+// q-value, in the order in which they are supplied.
+func bestOf(weighted map[string]float64, actionIDs []string) []string {
+	bestActions := []string{}
+	bestValue := -1 * math.MaxFloat64
+	for _, actionID := range actionIDs {
+		value := weighted[actionID]
+		if value > bestValue {
+			bestActions = []string{actionID}
+			bestValue = value
+		} else if value == bestValue {
+			bestActions = append(bestActions, actionID)
+		}
+	}
+	return bestActions
+}
+
+// applyActionWeights records any of a state's possible actions that are not
+// yet in the store, and updates the weighted q-value of each of the state's
+// actions.
 func (a *BayesianAgent) applyActionWeights(state iface.Stater) {
-	rawValueSum := 0.0
-	existingActionCount := 0.0
+	_, siblings, weighted := a.weigh(state)
 	for _, action := range state.PossibleActions() {
-		stats, found := a.qmap.GetStats(state, action)
-		if !found {
-			a.qmap.UpdateStats(state, action, new(ActionStats))
-		} else {
-			rawValueSum += nanToZero(stats.QValueRaw())
-			existingActionCount++
+		if _, found := a.store.GetStats(state, action); !found {
+			a.store.UpdateStats(state, action, siblings[action.ID()])
 		}
 	}
+	for actionID, stats := range siblings {
+		stats.SetQValueWeighted(weighted[actionID])
+	}
+}
 
-	mean := qlmath.SafeDivide(rawValueSum, existingActionCount)
-	for _, stats := range a.qmap.GetActionsForState(state) {
-		weighedMean := qlmath.BayesianAverage(
-			float64(a.primingThreshold),
-			float64(stats.Calls()),
-			nanToZero(mean),
-			nanToZero(stats.QValueRaw()),
-		)
-		stats.SetQValueWeighted(weighedMean)
+// weigh returns the prior and weighted q-value of each of a state's actions,
+// along with the stats of the actions. The stats of possible actions that are
+// not yet in the store are new, and are not added to the store. Priors are
+// estimated from the store alone, so that new actions do not influence the
+// priors of their siblings.
+func (a *BayesianAgent) weigh(state iface.Stater) (priors map[string]float64, siblings map[string]iface.ActionStatter, weighted map[string]float64) {
+	recorded := a.store.GetActionsForState(state)
+	priors = make(map[string]float64)
+	siblings = make(map[string]iface.ActionStatter, len(recorded))
+	for actionID, stats := range recorded {
+		priors[actionID] = a.prior.Estimate(a.store, state, actionID)
+		siblings[actionID] = stats
+	}
+	for _, action := range state.PossibleActions() {
+		if _, found := siblings[action.ID()]; !found {
+			priors[action.ID()] = a.prior.Estimate(a.store, state, action.ID())
+			siblings[action.ID()] = new(ActionStats)
+		}
 	}
+
+	now := a.now()
+	weighted = make(map[string]float64, len(siblings))
+	for actionID, stats := range siblings {
+		weighted[actionID] = a.weighter.Weight(WeightInput{
+			PrimingThreshold: float64(a.primingThreshold),
+			Calls:            a.effectiveCalls(stats, now),
+			Prior:            nanToZero(priors[actionID]),
+			Raw:              nanToZero(stats.QValueRaw()),
+			Stats:            stats,
+			Siblings:         siblings,
+		})
+	}
+	return
+}
+
+// candidates returns the IDs of a state's possible actions that are permitted
+// by allowed (if not nil), in a consistent order, so that the outcome of a
+// tie-break depends only on the agent's random source.
+func candidates(state iface.Stater, allowed ActionMask) []string {
+	actionIDs := []string{}
+	seen := make(map[string]bool)
+	for _, action := range state.PossibleActions() {
+		if seen[action.ID()] || (allowed != nil && !allowed(action)) {
+			continue
+		}
+		seen[action.ID()] = true
+		actionIDs = append(actionIDs, action.ID())
+	}
+	sort.Strings(actionIDs)
+	return actionIDs
 }
 
 func nanToZero(f float64) float64 {
@@ -183,9 +568,13 @@ func nanToZero(f float64) float64 {
 	return f
 }
 
-// getBestValue returns the best possible q-value for a state.
+// getBestValue returns the best q-value of a state's possible actions.
 func (a *BayesianAgent) getBestValue(state iface.Stater) (bestQValue float64) {
-	for _, stat := range a.qmap.GetActionsForState(state) {
+	for _, action := range state.PossibleActions() {
+		stat, found := a.store.GetStats(state, action)
+		if !found {
+			continue
+		}
 		q := nanToZero(stat.QValueWeighted())
 		if q > bestQValue {
 			bestQValue = q
@@ -196,32 +585,123 @@ func (a *BayesianAgent) getBestValue(state iface.Stater) (bestQValue float64) {
 
 // AgentContext provides information about the internal conditions of an Agent.
 type AgentContext struct {
-	LearningRate     float64
+	// LearningRate is the initial rate of the agent's learning rate schedule.
+	LearningRate float64
+	// LearningRateSchedule is omitted when the agent's learning rate is
+	// constant, in which case LearningRate fully describes it.
+	LearningRateSchedule *Schedule `json:",omitempty"`
+	// Steps is the number of transitions the agent has learned from.
+	Steps            int `json:",omitempty"`
 	DiscountFactor   float64
 	PrimingThreshold int
 	QValues          map[string]map[string]iface.ActionStatter
+	// Exploration is the agent's exploration schedule, which is omitted when
+	// the agent does not explore.
+	Exploration *Schedule `json:",omitempty"`
+	// RandState is the state of the agent's random source, if the source
+	// supports capturing its state.
+	RandState []byte `json:",omitempty"`
 }
 
 // GetAgentContext provides information about the internal conditions of the
 // Agent. It is intended to allow the current state of the Agent to be
 // serialized without exposing fields that should remain private.
+//
+// GetAgentContext panics if the agent's random source implements
+// encoding.BinaryMarshaler and fails to marshal its state. Sources that do
+// not implement encoding.BinaryMarshaler are skipped, and Source never fails,
+// so only a custom source can cause the panic. Callers that set such a source
+// should use CaptureAgentContext, which returns the error instead.
 func (a *BayesianAgent) GetAgentContext() AgentContext {
-	return AgentContext{
-		LearningRate:     a.learningRate,
+	c, err := a.CaptureAgentContext()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// CaptureAgentContext provides information about the internal conditions of
+// the Agent, as GetAgentContext does. If the agent's random source implements
+// encoding.BinaryMarshaler, its state is captured as the context's RandState,
+// and an error is returned if the state can not be captured, since the
+// context could not otherwise reproduce the agent's random choices.
+func (a *BayesianAgent) CaptureAgentContext() (AgentContext, error) {
+	c := AgentContext{
+		LearningRate:     a.learningRate.Initial,
+		Steps:            a.steps,
 		DiscountFactor:   a.discountFactor,
 		PrimingThreshold: a.primingThreshold,
-		QValues:          a.qmap.Data,
+		QValues:          a.store.GetData(),
+	}
+	if a.learningRate.Kind != ConstantSchedule {
+		schedule := a.learningRate
+		c.LearningRateSchedule = &schedule
 	}
+	if a.exploration != nil {
+		exploration := *a.exploration
+		c.Exploration = &exploration
+	}
+	if m, ok := a.source.(encoding.BinaryMarshaler); ok {
+		state, err := m.MarshalBinary()
+		if err != nil {
+			return AgentContext{}, fmt.Errorf("failed to capture random state: %v", err)
+		}
+		c.RandState = state
+	}
+	return c, nil
 }
 
 // SetAgentContext sets the internal conditions of the Agent based on a
 // pre-existsing AgentContext. This is provided to facilitate hydrating an
 // Agent without exposing fields that should remain private.
+//
+// SetAgentContext panics if the agent's random source implements
+// encoding.BinaryUnmarshaler and can not restore the context's RandState,
+// such as a RandState that is corrupt or was captured from a different kind
+// of source. Contexts without a RandState, and sources that do not implement
+// encoding.BinaryUnmarshaler, are skipped. Callers restoring contexts they do
+// not control should use RestoreAgentContext, which returns the error
+// instead.
 func (a *BayesianAgent) SetAgentContext(c AgentContext) {
-	a.learningRate = c.LearningRate
+	if err := a.RestoreAgentContext(c); err != nil {
+		panic(err)
+	}
+}
+
+// RestoreAgentContext sets the internal conditions of the Agent based on a
+// pre-existing AgentContext, as SetAgentContext does.
+// If the context includes RandState, and the agent's random source supports
+// restoring its state, the source continues from the captured state.
+// Otherwise the agent's random source is left unchanged.
+// An error is returned, and the agent is left unchanged, if the RandState can
+// not be restored, since the agent would otherwise silently make different
+// random choices than the agent from which the context was captured.
+func (a *BayesianAgent) RestoreAgentContext(c AgentContext) error {
+	if u, ok := a.source.(encoding.BinaryUnmarshaler); ok && c.RandState != nil {
+		if err := u.UnmarshalBinary(c.RandState); err != nil {
+			return fmt.Errorf("failed to restore random state: %v", err)
+		}
+		a.rng = rand.New(a.source)
+	}
+	a.learningRate = ConstantRate(c.LearningRate)
+	if c.LearningRateSchedule != nil {
+		a.learningRate = *c.LearningRateSchedule
+	}
+	a.steps = c.Steps
+	a.exploration = nil
+	if c.Exploration != nil {
+		exploration := *c.Exploration
+		a.exploration = &exploration
+	}
 	a.discountFactor = c.DiscountFactor
 	a.primingThreshold = c.PrimingThreshold
-	a.qmap.Data = c.QValues
+	a.observeData(a.store.GetData(), true)
+	a.store.SetData(c.QValues)
+	a.observeData(a.store.GetData(), false)
+	return nil
 }
 
-var _ iface.Agenter = (*BayesianAgent)(nil)
+var (
+	_ iface.Agenter    = (*BayesianAgent)(nil)
+	_ EvictionNotifier = (*BayesianAgent)(nil)
+)
@@ -3,6 +3,7 @@ package qlearning_test
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
@@ -96,6 +97,7 @@ func Test_BayesianAgentLearn(t *testing.T) {
 	action3.EXPECT().ID().Return("Z").AnyTimes()
 
 	ba := qlearning.NewBayesianAgent(10, 1, 0)
+	ba.SetSeed(42)
 	previousState := agent.NewMockStater(mc)
 	previousState.EXPECT().ID().Return("A").AnyTimes()
 	previousState.EXPECT().PossibleActions().Return(
@@ -120,15 +122,32 @@ func Test_BayesianAgentLearn(t *testing.T) {
 
 	result := ba.GetAgentContext()
 	actualJSON, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	randState, err := qlearning.NewSource(42).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	expected := qlearning.AgentContext{
 		LearningRate:     1,
+		Steps:            2,
 		DiscountFactor:   0,
 		PrimingThreshold: 10,
 		QValues: map[string]map[string]iface.ActionStatter{
 			"A": map[string]iface.ActionStatter{
-				"X": &qlearning.ActionStats{CallCount: 1, QRaw: 1, QWeighted: 0.6969696969696969},
-				"Y": &qlearning.ActionStats{CallCount: 1, QRaw: 1, QWeighted: 0.6969696969696969},
+				"X": &qlearning.ActionStats{
+					CallCount: 1, QRaw: 1, QWeighted: 0.6969696969696969,
+					Rewards: qlearning.RunningStats{Count: 1, Mean: 1},
+					Targets: qlearning.RunningStats{Count: 1, Mean: 1},
+				},
+				"Y": &qlearning.ActionStats{
+					CallCount: 1, QRaw: 1, QWeighted: 0.6969696969696969,
+					Rewards: qlearning.RunningStats{Count: 1, Mean: 1},
+					Targets: qlearning.RunningStats{Count: 1, Mean: 1},
+				},
 				"Z": &qlearning.ActionStats{CallCount: 0, QRaw: 0, QWeighted: 0.66666666666666666},
 			},
 			"B": map[string]iface.ActionStatter{
@@ -137,6 +156,7 @@ func Test_BayesianAgentLearn(t *testing.T) {
 				"Z": &qlearning.ActionStats{CallCount: 0, QRaw: 0, QWeighted: 0},
 			},
 		},
+		RandState: randState,
 	}
 
 	expectedJSON, err := json.Marshal(expected)
@@ -148,6 +168,96 @@ func Test_BayesianAgentLearn(t *testing.T) {
 
 }
 
+func Test_BayesianAgentSeeding(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	actions := make([]iface.Actioner, 10)
+	for i := range actions {
+		action := agent.NewMockActioner(mc)
+		action.EXPECT().ID().Return(fmt.Sprint(i)).AnyTimes()
+		actions[i] = action
+	}
+	state := agent.NewMockStater(mc)
+	state.EXPECT().ID().Return("A").AnyTimes()
+	state.EXPECT().PossibleActions().Return(actions).AnyTimes()
+	state.EXPECT().GetAction(gomock.Any()).DoAndReturn(func(id string) (iface.Actioner, error) {
+		var i int
+		fmt.Sscan(id, &i)
+		return actions[i], nil
+	}).AnyTimes()
+
+	recommendations := func(a *qlearning.BayesianAgent, n int) (ids []string) {
+		for i := 0; i < n; i++ {
+			action, err := a.RecommendAction(state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, action.ID())
+		}
+		return
+	}
+
+	a := qlearning.NewBayesianAgent(1, .5, .5)
+	a.SetSeed(7)
+	b := qlearning.NewBayesianAgent(1, .5, .5)
+	b.SetSeed(7)
+	assert.Equal(t, recommendations(a, 20), recommendations(b, 20))
+
+	// An agent restored from a snapshot continues the same random sequence.
+	snapshot := a.GetAgentContext()
+	expected := recommendations(a, 20)
+	restored := qlearning.NewBayesianAgent(0, 0, 0)
+	restored.SetAgentContext(snapshot)
+	assert.Equal(t, expected, recommendations(restored, 20))
+
+	// A corrupt random state is reported, and leaves the agent unchanged.
+	corrupt := snapshot
+	corrupt.RandState = []byte{1}
+	corrupt.Steps = 99
+	assert.EqualError(t, restored.RestoreAgentContext(corrupt), "failed to restore random state: invalid source state: expected 8 bytes, got 1")
+	assert.Equal(t, 0, restored.GetAgentContext().Steps)
+	assert.Panics(t, func() { restored.SetAgentContext(corrupt) })
+}
+
+// unmarshalableSource is a rand.Source whose state can never be captured.
+type unmarshalableSource struct {
+	rand.Source
+}
+
+func (unmarshalableSource) MarshalBinary() ([]byte, error) {
+	return nil, fmt.Errorf("state is unavailable")
+}
+
+func Test_BayesianAgentCaptureAgentContextError(t *testing.T) {
+	a := qlearning.NewBayesianAgent(1, .5, .5)
+	a.SetRandSource(unmarshalableSource{rand.NewSource(1)})
+
+	_, err := a.CaptureAgentContext()
+	assert.EqualError(t, err, "failed to capture random state: state is unavailable")
+	assert.Panics(t, func() { a.GetAgentContext() })
+
+	// A source that can not capture or restore its state is skipped.
+	a.SetRandSource(rand.NewSource(1))
+	assert.Nil(t, a.GetAgentContext().RandState)
+	assert.NotPanics(t, func() { a.SetAgentContext(qlearning.AgentContext{RandState: []byte{1}}) })
+}
+
+func Test_SourceMarshalBinary(t *testing.T) {
+	s := qlearning.NewSource(1)
+	s.Uint64()
+	state, err := s.MarshalBinary()
+	if !assert.NoError(t, err) {
+		return
+	}
+	next := s.Int63()
+
+	restored := qlearning.NewSource(0)
+	assert.NoError(t, restored.UnmarshalBinary(state))
+	assert.Equal(t, next, restored.Int63())
+	assert.EqualError(t, restored.UnmarshalBinary([]byte{1}), "invalid source state: expected 8 bytes, got 1")
+}
+
 func Test_Transition(t *testing.T) {
 
 	testCases := []struct {
@@ -0,0 +1,365 @@
+package qlearning
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Rough estimates of the memory, in bytes, occupied by each state and each
+// action of a BoundedStore, excluding the bytes of their IDs.
+const (
+	stateOverheadBytes  = 160
+	actionOverheadBytes = 120
+)
+
+// EvictionDeferrer is an optional interface that a QStorer may implement to
+// postpone evictions while the agent updates several states within a single
+// call to Learn (such as a state and its ancestors), so that no state is
+// evicted partway through its update. Calls may be nested; evictions resume
+// once every DeferEviction has been matched by a ResumeEviction.
+type EvictionDeferrer interface {
+	DeferEviction()
+	ResumeEviction()
+}
+
+// ClockSetter is an optional interface that a QStorer may implement to read
+// the current time from the agent's clock (see WithClock).
+type ClockSetter interface {
+	SetClock(clock func() time.Time)
+}
+
+// EvictionNotifier is an optional interface that a QStorer may implement to
+// inform the agent of the states it evicts, so that the agent can discard
+// what it holds about them besides their stats (such as their drift
+// detectors).
+type EvictionNotifier interface {
+	AddEvictListener(listener EvictFunc)
+}
+
+// EvictFunc receives the stats of every action of a state that is being
+// evicted from a BoundedStore, so that they can be archived.
+type EvictFunc func(stateID string, actions map[string]iface.ActionStatter)
+
+// EvictionPolicy determines when a BoundedStore evicts states.
+type EvictionPolicy struct {
+	// MaxStates is the greatest number of states retained. When a new state
+	// would exceed the limit, the least recently used unprotected state is
+	// evicted. Zero means no limit. Otherwise, MaxStates must be at least 2,
+	// since an agent works with two states at once while learning.
+	MaxStates int
+	// TTL is the duration after its last use that a state expires and is
+	// evicted. Zero means states never expire.
+	TTL time.Duration
+	// MinVisits protects any state whose actions have been called at least
+	// this many times in total from eviction. Zero means no state is
+	// protected. If every state is protected, the store can exceed MaxStates.
+	MinVisits int
+	// OnEvict, if not nil, is called with each state as it is evicted. It is
+	// not called for the states discarded by SetData.
+	OnEvict EvictFunc
+}
+
+// StoreStats describes the contents and history of a BoundedStore.
+type StoreStats struct {
+	States          int
+	Actions         int
+	ProtectedStates int
+	// EstimatedBytes is a rough estimate of the memory occupied by the
+	// store's states and actions.
+	EstimatedBytes int
+	// Evicted is the number of states evicted to respect MaxStates.
+	Evicted int
+	// Expired is the number of states evicted because their TTL elapsed.
+	Expired int
+}
+
+type storeEntry struct {
+	stateID   string
+	lastVisit time.Time
+	// calls holds the number of calls of each action as of its last update,
+	// and visits is their total.
+	calls  map[string]int
+	visits int
+	// element is the entry's position in the store's recency list, or nil if
+	// the entry is protected.
+	element *list.Element
+}
+
+// BoundedStore is an iface.QStorer that bounds its memory use by evicting
+// states that are rarely used, according to an EvictionPolicy. Every method
+// that accesses an existing state (other than GetData) counts as a use of the
+// state, but only UpdateStats adds states to the store, so reading the stats
+// of an unknown state never causes an eviction. A state's visits are counted
+// from the calls of its actions as of their last UpdateStats. Expired states
+// are evicted whenever a state is accessed, or on demand via Sweep. Evictions
+// are deferred while the agent is learning (see EvictionDeferrer). A
+// BoundedStore is not safe for concurrent use.
+type BoundedStore struct {
+	policy    EvictionPolicy
+	clock     func() time.Time
+	listeners []EvictFunc
+	data      map[string]map[string]iface.ActionStatter
+	entries   map[string]*storeEntry
+	// recency orders the unprotected states from most to least recently
+	// used. Protected states can not be evicted, so are not listed.
+	recency   *list.List
+	protected int
+	latest    string
+	evicted   int
+	expired   int
+	deferred  int
+}
+
+// NewBoundedStore returns a reference to a new, empty BoundedStore, or an
+// error if the policy is invalid.
+func NewBoundedStore(policy EvictionPolicy) (*BoundedStore, error) {
+	if policy.MaxStates < 0 || policy.MaxStates == 1 {
+		return nil, fmt.Errorf("maximum states must be 0 or at least 2, got %v", policy.MaxStates)
+	}
+	if policy.TTL < 0 {
+		return nil, fmt.Errorf("ttl must not be negative, got %v", policy.TTL)
+	}
+	if policy.MinVisits < 0 {
+		return nil, fmt.Errorf("minimum visits must not be negative, got %v", policy.MinVisits)
+	}
+	s := &BoundedStore{policy: policy, clock: time.Now}
+	s.SetData(map[string]map[string]iface.ActionStatter{})
+	return s, nil
+}
+
+// GetStats returns the stats for a given state and action.
+// If a the specified action has not been recorded for the given state, the
+// method will return nil, false.
+func (s *BoundedStore) GetStats(state iface.Stater, action iface.Actioner) (stats iface.ActionStatter, found bool) {
+	stats, found = s.GetActionsForState(state)[action.ID()]
+	return
+}
+
+// UpdateStats updates the stats of a given state and action, adding the
+// state to the store if necessary.
+func (s *BoundedStore) UpdateStats(state iface.Stater, action iface.Actioner, stats iface.ActionStatter) {
+	stateID := state.ID()
+	entry, found := s.entries[stateID]
+	if !found {
+		entry = s.add(stateID, make(map[string]iface.ActionStatter))
+	}
+	s.data[stateID][action.ID()] = stats
+	s.count(entry, action.ID(), stats.Calls())
+	s.touch(entry)
+	if !found && s.deferred == 0 {
+		s.enforceLimit(stateID)
+	}
+}
+
+// GetActionsForState returns the actions associated with a given state, or
+// nil if the state is not in the store.
+func (s *BoundedStore) GetActionsForState(state iface.Stater) map[string]iface.ActionStatter {
+	entry, found := s.entries[state.ID()]
+	if !found {
+		return nil
+	}
+	s.touch(entry)
+	return s.data[state.ID()]
+}
+
+// DeleteStats removes the stats for a given state and action, if any.
+func (s *BoundedStore) DeleteStats(state iface.Stater, actionID string) {
+	entry, found := s.entries[state.ID()]
+	if !found {
+		return
+	}
+	delete(s.data[state.ID()], actionID)
+	s.count(entry, actionID, 0)
+	delete(entry.calls, actionID)
+	s.touch(entry)
+}
+
+// GetData returns the stats of every action recorded for every state.
+func (s *BoundedStore) GetData() map[string]map[string]iface.ActionStatter {
+	return s.data
+}
+
+// SetData replaces every recorded stat with the supplied data. Every state is
+// considered to have been used at the time SetData is called, in descending
+// order of ID. SetData never evicts a state (nor calls OnEvict). If the data
+// contains more states than the policy allows, the least recently used states
+// are evicted when the next state is added to the store.
+func (s *BoundedStore) SetData(data map[string]map[string]iface.ActionStatter) {
+	s.data = make(map[string]map[string]iface.ActionStatter, len(data))
+	s.entries = make(map[string]*storeEntry, len(data))
+	s.recency = list.New()
+	s.protected = 0
+	s.latest = ""
+
+	stateIDs := make([]string, 0, len(data))
+	for stateID := range data {
+		stateIDs = append(stateIDs, stateID)
+	}
+	sort.Strings(stateIDs)
+	for _, stateID := range stateIDs {
+		entry := s.add(stateID, data[stateID])
+		for actionID, stats := range data[stateID] {
+			s.count(entry, actionID, stats.Calls())
+		}
+	}
+}
+
+// SetClock sets the function from which the store reads the current time,
+// which defaults to time.Now. An agent created by NewBayesianAgentWithOptions
+// sets its store's clock to its own.
+func (s *BoundedStore) SetClock(clock func() time.Time) {
+	s.clock = clock
+}
+
+// AddEvictListener registers a function to be called with each state as it
+// is evicted, after the policy's OnEvict. An agent created by
+// NewBayesianAgentWithOptions registers itself with its store.
+func (s *BoundedStore) AddEvictListener(listener EvictFunc) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// DeferEviction postpones evictions until the matching call to
+// ResumeEviction. The store may exceed MaxStates in the meantime.
+func (s *BoundedStore) DeferEviction() {
+	s.deferred++
+}
+
+// ResumeEviction ends a matching call to DeferEviction. Once evictions are no
+// longer deferred, expired states are swept, and the least recently used
+// states (other than the most recently used) are evicted until the store
+// respects MaxStates.
+func (s *BoundedStore) ResumeEviction() {
+	if s.deferred == 0 {
+		return
+	}
+	s.deferred--
+	if s.deferred > 0 {
+		return
+	}
+	s.Sweep()
+	s.enforceLimit(s.latest)
+}
+
+// Sweep evicts every state whose TTL has elapsed, and returns the number of
+// states evicted.
+func (s *BoundedStore) Sweep() int {
+	if s.policy.TTL == 0 {
+		return 0
+	}
+	deadline := s.clock().Add(-s.policy.TTL)
+	swept := 0
+	for e := s.recency.Back(); e != nil; e = s.recency.Back() {
+		if !e.Value.(*storeEntry).lastVisit.Before(deadline) {
+			break
+		}
+		s.evict(e.Value.(*storeEntry))
+		s.expired++
+		swept++
+	}
+	return swept
+}
+
+// Stats returns a description of the store's contents and history.
+func (s *BoundedStore) Stats() StoreStats {
+	stats := StoreStats{
+		States:          len(s.data),
+		ProtectedStates: s.protected,
+		Evicted:         s.evicted,
+		Expired:         s.expired,
+	}
+	for stateID, actions := range s.data {
+		stats.Actions += len(actions)
+		stats.EstimatedBytes += len(stateID) + stateOverheadBytes
+		for actionID := range actions {
+			stats.EstimatedBytes += len(actionID) + actionOverheadBytes
+		}
+	}
+	return stats
+}
+
+// add adds a state to the store as its most recently used state.
+func (s *BoundedStore) add(stateID string, actions map[string]iface.ActionStatter) *storeEntry {
+	entry := &storeEntry{
+		stateID:   stateID,
+		lastVisit: s.clock(),
+		calls:     make(map[string]int, len(actions)),
+	}
+	entry.element = s.recency.PushFront(entry)
+	s.data[stateID] = actions
+	s.entries[stateID] = entry
+	s.latest = stateID
+	return entry
+}
+
+// count records the number of calls of an action of a state, and protects or
+// unprotects the state accordingly.
+func (s *BoundedStore) count(entry *storeEntry, actionID string, calls int) {
+	entry.visits += calls - entry.calls[actionID]
+	entry.calls[actionID] = calls
+	protected := s.policy.MinVisits > 0 && entry.visits >= s.policy.MinVisits
+	switch {
+	case protected && entry.element != nil:
+		s.recency.Remove(entry.element)
+		entry.element = nil
+		s.protected++
+	case !protected && entry.element == nil:
+		entry.element = s.recency.PushFront(entry)
+		s.protected--
+	}
+}
+
+// touch records a use of a state, and evicts any states that have expired.
+func (s *BoundedStore) touch(entry *storeEntry) {
+	entry.lastVisit = s.clock()
+	if entry.element != nil {
+		s.recency.MoveToFront(entry.element)
+	}
+	s.latest = entry.stateID
+	if s.deferred == 0 {
+		s.Sweep()
+	}
+}
+
+// enforceLimit evicts the least recently used unprotected states, other than
+// keep, until the store respects MaxStates.
+func (s *BoundedStore) enforceLimit(keep string) {
+	if s.policy.MaxStates == 0 {
+		return
+	}
+	for e := s.recency.Back(); len(s.data) > s.policy.MaxStates && e != nil; e = s.recency.Back() {
+		entry := e.Value.(*storeEntry)
+		if entry.stateID == keep {
+			if e = e.Prev(); e == nil {
+				return
+			}
+			entry = e.Value.(*storeEntry)
+		}
+		s.evict(entry)
+		s.evicted++
+	}
+}
+
+func (s *BoundedStore) evict(entry *storeEntry) {
+	actions := s.data[entry.stateID]
+	s.recency.Remove(entry.element)
+	delete(s.entries, entry.stateID)
+	delete(s.data, entry.stateID)
+	if s.policy.OnEvict != nil {
+		s.policy.OnEvict(entry.stateID, actions)
+	}
+	for _, listener := range s.listeners {
+		listener(entry.stateID, actions)
+	}
+}
+
+var (
+	_ iface.QStorer      = (*BoundedStore)(nil)
+	_ iface.StatsDeleter = (*BoundedStore)(nil)
+	_ EvictionDeferrer   = (*BoundedStore)(nil)
+	_ ClockSetter        = (*BoundedStore)(nil)
+	_ EvictionNotifier   = (*BoundedStore)(nil)
+)
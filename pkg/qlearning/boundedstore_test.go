@@ -0,0 +1,231 @@
+package qlearning_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newNamedState(mc *gomock.Controller, id string) iface.Stater {
+	state := agent.NewMockStater(mc)
+	state.EXPECT().ID().Return(id).AnyTimes()
+	return state
+}
+
+func Test_BoundedStoreLRU(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	action := agent.NewMockActioner(mc)
+	action.EXPECT().ID().Return("X").AnyTimes()
+
+	evicted := []string{}
+	store, err := qlearning.NewBoundedStore(qlearning.EvictionPolicy{
+		MaxStates: 2,
+		OnEvict: func(stateID string, actions map[string]iface.ActionStatter) {
+			evicted = append(evicted, stateID)
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	a, b, c := newNamedState(mc, "A"), newNamedState(mc, "B"), newNamedState(mc, "C")
+	store.UpdateStats(a, action, &qlearning.ActionStats{CallCount: 1})
+	store.UpdateStats(b, action, &qlearning.ActionStats{CallCount: 1})
+	store.GetStats(a, action)
+	store.UpdateStats(c, action, &qlearning.ActionStats{CallCount: 1})
+
+	assert.Equal(t, []string{"B"}, evicted)
+	assert.Contains(t, store.GetData(), "A")
+	assert.Contains(t, store.GetData(), "C")
+
+	stats := store.Stats()
+	assert.Equal(t, 2, stats.States)
+	assert.Equal(t, 2, stats.Actions)
+	assert.Equal(t, 1, stats.Evicted)
+	assert.True(t, stats.EstimatedBytes > 0)
+}
+
+func Test_BoundedStoreReadsAndSetData(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	action := agent.NewMockActioner(mc)
+	action.EXPECT().ID().Return("X").AnyTimes()
+
+	evicted := []string{}
+	store, err := qlearning.NewBoundedStore(qlearning.EvictionPolicy{
+		MaxStates: 2,
+		OnEvict: func(stateID string, actions map[string]iface.ActionStatter) {
+			evicted = append(evicted, stateID)
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Reading unknown states does not add them to the store.
+	a, b, c := newNamedState(mc, "A"), newNamedState(mc, "B"), newNamedState(mc, "C")
+	_, found := store.GetStats(a, action)
+	assert.False(t, found)
+	assert.Nil(t, store.GetActionsForState(b))
+	store.DeleteStats(c, "X")
+	assert.Empty(t, store.GetData())
+
+	// SetData may exceed MaxStates without evicting anything, until the next
+	// state is added.
+	store.SetData(map[string]map[string]iface.ActionStatter{
+		"A": {"X": &qlearning.ActionStats{CallCount: 1}},
+		"B": {"X": &qlearning.ActionStats{CallCount: 1}},
+		"C": {"X": &qlearning.ActionStats{CallCount: 1}},
+	})
+	assert.Empty(t, evicted)
+	assert.Equal(t, 3, store.Stats().States)
+
+	store.UpdateStats(newNamedState(mc, "D"), action, &qlearning.ActionStats{CallCount: 1})
+	assert.Equal(t, []string{"A", "B"}, evicted)
+}
+
+func Test_BoundedStoreTTLAndProtection(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	action := agent.NewMockActioner(mc)
+	action.EXPECT().ID().Return("X").AnyTimes()
+
+	now := time.Unix(0, 0)
+	store, err := qlearning.NewBoundedStore(qlearning.EvictionPolicy{
+		TTL:       time.Hour,
+		MinVisits: 5,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	store.SetClock(func() time.Time { return now })
+
+	a, b := newNamedState(mc, "A"), newNamedState(mc, "B")
+	store.UpdateStats(a, action, &qlearning.ActionStats{CallCount: 1})
+	store.UpdateStats(b, action, &qlearning.ActionStats{CallCount: 5})
+
+	now = now.Add(30 * time.Minute)
+	assert.Equal(t, 0, store.Sweep())
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, 1, store.Sweep())
+	assert.NotContains(t, store.GetData(), "A")
+	assert.Contains(t, store.GetData(), "B", "B is protected by its visits")
+
+	stats := store.Stats()
+	assert.Equal(t, 1, stats.Expired)
+	assert.Equal(t, 1, stats.ProtectedStates)
+}
+
+func Test_BoundedStoreWithAgent(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, y, a, b, terminal := newPriorFixture(mc)
+
+	c := agent.NewMockStater(mc)
+	c.EXPECT().ID().Return("C").AnyTimes()
+	c.EXPECT().PossibleActions().Return([]iface.Actioner{x, y}).AnyTimes()
+	c.EXPECT().GetAction("X").Return(x, nil).AnyTimes()
+	c.EXPECT().GetAction("Y").Return(y, nil).AnyTimes()
+
+	store, err := qlearning.NewBoundedStore(qlearning.EvictionPolicy{MaxStates: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithStore(store))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ba.Learn(a, x, terminal, 1)
+	ba.Learn(b, x, terminal, 1)
+
+	// Recommending an action for an unknown state does not add it to the
+	// store, so does not evict A.
+	_, err = ba.RecommendAction(c)
+	assert.NoError(t, err)
+	assert.Contains(t, ba.GetAgentContext().QValues, "A")
+
+	ba.Learn(c, x, terminal, 1)
+	assert.Equal(t, 2, store.Stats().States)
+	assert.Contains(t, ba.GetAgentContext().QValues, "C")
+	assert.NotContains(t, ba.GetAgentContext().QValues, "A")
+}
+
+func Test_BoundedStoreUsesAgentClock(t *testing.T) {
+	actions := []iface.Actioner{testAction("X")}
+	a := &treeState{id: "A", actions: actions}
+	terminal := &treeState{id: "terminal"}
+
+	now := time.Unix(0, 0)
+	store, err := qlearning.NewBoundedStore(qlearning.EvictionPolicy{TTL: time.Hour})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithStore(store),
+		qlearning.WithClock(func() time.Time { return now }),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, testAction("X"), terminal, 1)
+
+	now = now.Add(30 * time.Minute)
+	assert.Equal(t, 0, store.Sweep())
+	now = now.Add(time.Hour)
+	assert.Equal(t, 1, store.Sweep())
+}
+
+func Test_NewBoundedStoreInvalid(t *testing.T) {
+	for _, policy := range []qlearning.EvictionPolicy{
+		{MaxStates: -1},
+		{MaxStates: 1},
+		{TTL: -time.Second},
+		{MinVisits: -1},
+	} {
+		_, err := qlearning.NewBoundedStore(policy)
+		assert.Error(t, err, "%+v", policy)
+	}
+}
+
+func Test_BoundedStoreKeepsStateDuringLearn(t *testing.T) {
+	actions := []iface.Actioner{testAction("X"), testAction("Y")}
+	group := &treeState{id: "group", actions: actions}
+	leaf := &treeState{id: "leaf", parent: group, actions: actions}
+	terminal := &treeState{id: "terminal"}
+
+	store, err := qlearning.NewBoundedStore(qlearning.EvictionPolicy{MaxStates: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	a, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithStore(store),
+		qlearning.WithHierarchy(0),
+		qlearning.WithDiscount(0),
+		qlearning.WithLearningRate(1),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Pooling each transition into the group must not evict the leaf while
+	// the leaf is being updated, which would lose the stats of its other
+	// actions.
+	a.Learn(leaf, testAction("Y"), terminal, 5)
+	a.Learn(leaf, testAction("X"), terminal, 1)
+
+	stats := store.GetData()["leaf"]
+	if assert.Len(t, stats, 2) {
+		assert.Equal(t, 1, stats["X"].Calls())
+		assert.Equal(t, 1, stats["Y"].Calls())
+		assert.Equal(t, 5.0, stats["Y"].QValueRaw())
+	}
+	assert.True(t, store.Stats().States <= 2)
+}
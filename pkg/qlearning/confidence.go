@@ -0,0 +1,122 @@
+package qlearning
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// ErrLowConfidence is returned (wrapped) by RecommendAction when the agent's
+// ConfidencePolicy is not satisfied and no fallback has been provided. Use
+// errors.Is to detect it.
+var ErrLowConfidence = errors.New("insufficient confidence to recommend an action")
+
+// ConfidencePolicy describes the evidence the agent requires before it will
+// recommend the best action for a state. See WithConfidencePolicy.
+type ConfidencePolicy struct {
+	// MinCalls is the number of times the best action must have been called.
+	// If the agent forgets (see Forgetting), the action's effective number of
+	// calls is compared with MinCalls, so that evidence that has largely been
+	// forgotten does not count toward confidence.
+	MinCalls int
+	// Z is the number of standard errors either side of the mean target of
+	// each action that form the action's confidence interval (see
+	// BayesianAgent.ConfidenceInterval). If Z is zero, confidence intervals are
+	// not considered.
+	Z float64
+	// MaxOverlap is the greatest fraction of the best action's confidence
+	// interval that may overlap the interval of any other action. An action
+	// that has never been observed is not compared with the best action,
+	// since there is no evidence that it is any better. An action observed
+	// once has no interval, and is considered to overlap entirely.
+	MaxOverlap float64
+	// Fallback, if not nil, is called to provide the action recommended when
+	// the policy is not satisfied. Otherwise, ErrLowConfidence is returned.
+	// Fallback is also called to compute the probability with which each
+	// action is recommended (see RankActions and Decision.Propensity), even
+	// when its action is not recommended, so it should return the same action
+	// for a given state and have no side effects.
+	Fallback func(state iface.Stater) (iface.Actioner, error)
+}
+
+func (p ConfidencePolicy) validate() error {
+	if p.MinCalls < 0 {
+		return fmt.Errorf("minimum calls must not be negative, got %v", p.MinCalls)
+	}
+	if !(p.Z >= 0) || math.IsInf(p.Z, 1) {
+		return fmt.Errorf("z must not be negative, got %v", p.Z)
+	}
+	if !(p.MaxOverlap >= 0 && p.MaxOverlap <= 1) {
+		return fmt.Errorf("maximum overlap must be between 0 and 1, got %v", p.MaxOverlap)
+	}
+	return nil
+}
+
+// check returns an error wrapping ErrLowConfidence if chosen, one of the best
+// actions of a decision, does not satisfy the policy.
+func (p ConfidencePolicy) check(state iface.Stater, d decision, chosen string) error {
+	best := d.actions[chosen]
+	if calls := d.calls[chosen]; calls < float64(p.MinCalls) {
+		return fmt.Errorf("state '%v': action '%v' has been called %.4g times, %v are required: %w",
+			state.ID(), chosen, calls, p.MinCalls, ErrLowConfidence)
+	}
+	if p.Z == 0 {
+		return nil
+	}
+
+	bestInterval, ok := interval(best, p.Z)
+	if !ok {
+		return fmt.Errorf("state '%v': action '%v' has too few observations to estimate confidence: %w",
+			state.ID(), chosen, ErrLowConfidence)
+	}
+	for _, actionID := range d.actionIDs {
+		if actionID == chosen || observations(d.actions[actionID]) == 0 {
+			continue
+		}
+		overlap := 1.0
+		if other, ok := interval(d.actions[actionID], p.Z); ok {
+			overlap = overlapOf(bestInterval, other)
+		}
+		if overlap > p.MaxOverlap {
+			return fmt.Errorf("state '%v': action '%v' overlaps action '%v' by %.4g: %w",
+				state.ID(), chosen, actionID, overlap, ErrLowConfidence)
+		}
+	}
+	return nil
+}
+
+// interval returns the confidence interval of the targets observed by stats,
+// or false if there are too few observations.
+func interval(stats iface.ActionStatter, z float64) (Interval, bool) {
+	observed, ok := stats.(ObservationStatter)
+	if !ok || observed.TargetStats().Count < 2 {
+		return Interval{}, false
+	}
+	return observed.TargetStats().ConfidenceInterval(z), true
+}
+
+// observations returns the number of times an action has been observed: the
+// number of targets recorded by stats that implement ObservationStatter, or
+// otherwise the number of calls.
+func observations(stats iface.ActionStatter) int {
+	if observed, ok := stats.(ObservationStatter); ok {
+		return observed.TargetStats().Count
+	}
+	return stats.Calls()
+}
+
+// overlapOf returns the fraction of a that overlaps b. If a has no width, the
+// fraction is 1 if a lies within b, otherwise 0.
+func overlapOf(a, b Interval) float64 {
+	width := a.High - a.Low
+	if width == 0 {
+		if a.Low >= b.Low && a.Low <= b.High {
+			return 1
+		}
+		return 0
+	}
+	shared := math.Min(a.High, b.High) - math.Max(a.Low, b.Low)
+	return math.Max(shared, 0) / width
+}
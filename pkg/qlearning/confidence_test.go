@@ -0,0 +1,182 @@
+package qlearning_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRecommendFixture returns a state A whose possible actions have the
+// supplied IDs, and which can provide any of them via GetAction, along with a
+// terminal state T with no actions.
+func newRecommendFixture(mc *gomock.Controller, actionIDs ...string) (actions map[string]iface.Actioner, state, terminal iface.Stater) {
+	actions = map[string]iface.Actioner{}
+	possible := []iface.Actioner{}
+	for _, id := range actionIDs {
+		action := agent.NewMockActioner(mc)
+		action.EXPECT().ID().Return(id).AnyTimes()
+		actions[id] = action
+		possible = append(possible, action)
+	}
+	stateA := agent.NewMockStater(mc)
+	stateA.EXPECT().ID().Return("A").AnyTimes()
+	stateA.EXPECT().PossibleActions().Return(possible).AnyTimes()
+	stateA.EXPECT().GetAction(gomock.Any()).DoAndReturn(func(id string) (iface.Actioner, error) {
+		return actions[id], nil
+	}).AnyTimes()
+	stateT := agent.NewMockStater(mc)
+	stateT.EXPECT().ID().Return("T").AnyTimes()
+	stateT.EXPECT().PossibleActions().Return([]iface.Actioner{}).AnyTimes()
+	return actions, stateA, stateT
+}
+
+func Test_ConfidencePolicyMinCalls(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	actions, state, terminal := newRecommendFixture(mc, "X", "Y")
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithDiscount(0),
+		qlearning.WithConfidencePolicy(qlearning.ConfidencePolicy{MinCalls: 2}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(state, actions["X"], terminal, 1)
+
+	_, err = ba.RecommendAction(state)
+	assert.True(t, errors.Is(err, qlearning.ErrLowConfidence), "unexpected error %v", err)
+
+	ba.Learn(state, actions["X"], terminal, 1)
+	action, err := ba.RecommendAction(state)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "X", action.ID())
+	}
+}
+
+func Test_ConfidencePolicyOverlap(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	actions, state, terminal := newRecommendFixture(mc, "X", "Y")
+
+	fallback := agent.NewMockActioner(mc)
+	fallback.EXPECT().ID().Return("fallback").AnyTimes()
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithLearningRate(.5),
+		qlearning.WithDiscount(0),
+		qlearning.WithConfidencePolicy(qlearning.ConfidencePolicy{
+			Z:          1.96,
+			MaxOverlap: .1,
+			Fallback: func(iface.Stater) (iface.Actioner, error) {
+				return fallback, nil
+			},
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	recommend := func() string {
+		action, err := ba.RecommendAction(state)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		return action.ID()
+	}
+
+	// Y is untried, so is not compared with X.
+	ba.Learn(state, actions["X"], terminal, 10)
+	ba.Learn(state, actions["X"], terminal, 11)
+	assert.Equal(t, "X", recommend())
+
+	// Y has been observed once, so has no interval, and overlaps X entirely.
+	ba.Learn(state, actions["Y"], terminal, 0)
+	assert.Equal(t, "fallback", recommend())
+
+	// Noisy observations of Y overlap X.
+	ba.Learn(state, actions["Y"], terminal, 12)
+	assert.Equal(t, "fallback", recommend())
+
+	// Consistent observations of Y separate it from X.
+	for i := 0; i < 20; i++ {
+		ba.Learn(state, actions["Y"], terminal, 1)
+		ba.Learn(state, actions["X"], terminal, 10)
+	}
+	assert.Equal(t, "X", recommend())
+}
+
+func Test_ConfidencePolicyFallbackCalledOnce(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	_, state, _ := newRecommendFixture(mc, "X", "Y")
+
+	fallback := agent.NewMockActioner(mc)
+	fallback.EXPECT().ID().Return("fallback").AnyTimes()
+	calls := 0
+	policy := qlearning.ConfidencePolicy{
+		MinCalls: 1,
+		Fallback: func(iface.Stater) (iface.Actioner, error) {
+			calls++
+			return fallback, nil
+		},
+	}
+
+	// Without a logger, the propensity of the recommendation is not needed,
+	// so the fallback is not consulted again to compute it.
+	ba, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithConfidencePolicy(policy))
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = ba.RecommendAction(state)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	_, decision, err := ba.RecommendDecision(state)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "fallback", decision.ActionID)
+		assert.Equal(t, 1.0, decision.Propensity)
+	}
+}
+
+func Test_ConfidencePolicyForgetting(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	actions, state, terminal := newRecommendFixture(mc, "X", "Y")
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithDiscount(0),
+		qlearning.WithForgetting(qlearning.Forgetting{HalfLifeSteps: 1}),
+		qlearning.WithConfidencePolicy(qlearning.ConfidencePolicy{MinCalls: 2}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(state, actions["X"], terminal, 1)
+	ba.Learn(state, actions["X"], terminal, 1)
+	ba.Learn(state, actions["X"], terminal, 1)
+
+	// X has been called three times, but its evidence halves with every
+	// step, so its effective calls are (1 + 1/2 + 1/4) / 2.
+	_, err = ba.RecommendAction(state)
+	assert.True(t, errors.Is(err, qlearning.ErrLowConfidence), "unexpected error %v", err)
+}
+
+func Test_WithConfidencePolicyInvalid(t *testing.T) {
+	for _, policy := range []qlearning.ConfidencePolicy{
+		{MinCalls: -1},
+		{Z: -1},
+		{MaxOverlap: 1.5},
+	} {
+		_, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithConfidencePolicy(policy))
+		assert.Error(t, err, "%+v", policy)
+	}
+}
@@ -0,0 +1,102 @@
+package qlearning
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Decision records a recommendation made by the agent, along with the
+// probability with which the recommended action was chosen. The time of a
+// decision is read from the agent's clock (see WithClock). Decisions are
+// intended to be logged (see DecisionLogger) so that other policies can later
+// be evaluated against them (see the ope package).
+type Decision struct {
+	// DecisionID uniquely identifies the decision.
+	DecisionID string
+	Time       time.Time
+	StateID    string
+	// Actions lists the IDs of the actions the agent chose between.
+	Actions []string
+	// ActionID is the ID of the recommended action.
+	ActionID string
+	// Propensity is the probability with which the agent would recommend the
+	// action, assuming its tie breaker chooses uniformly between tied actions
+	// (as the default tie breaker does). It accounts for exploration and the
+	// agent's ConfidencePolicy (see RankedAction.Probability).
+	Propensity float64
+}
+
+func newDecision(now time.Time, stateID string, actionIDs []string, actionID string, propensity float64) (Decision, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Decision{}, fmt.Errorf("failed to generate decision ID: %v", err)
+	}
+	return Decision{
+		DecisionID: hex.EncodeToString(id),
+		Time:       now,
+		StateID:    stateID,
+		Actions:    actionIDs,
+		ActionID:   actionID,
+		Propensity: propensity,
+	}, nil
+}
+
+// DecisionLogger records the decisions made by an agent.
+type DecisionLogger interface {
+	LogDecision(d Decision) error
+}
+
+// JSONDecisionLogger is a DecisionLogger that writes each decision to an
+// io.Writer as a line of JSON. It is safe for concurrent use.
+type JSONDecisionLogger struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONDecisionLogger returns a reference to a new JSONDecisionLogger that
+// writes to w.
+func NewJSONDecisionLogger(w io.Writer) *JSONDecisionLogger {
+	return &JSONDecisionLogger{encoder: json.NewEncoder(w)}
+}
+
+// LogDecision writes d as a line of JSON.
+func (l *JSONDecisionLogger) LogDecision(d Decision) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.encoder.Encode(d)
+}
+
+// WithDecisionLogger makes the agent log every recommendation it makes.
+// If a decision can not be logged, the recommendation fails.
+func WithDecisionLogger(logger DecisionLogger) Option {
+	return func(a *BayesianAgent) error {
+		if logger == nil {
+			return fmt.Errorf("decision logger must not be nil")
+		}
+		a.logger = logger
+		return nil
+	}
+}
+
+// RecommendDecision recommends an action for a given state, exactly as
+// RecommendAction does, and also returns the Decision that describes the
+// recommendation. The decision's ID can be used to correlate the
+// recommendation with its eventual outcome. If attribution is enabled (see
+// WithAttribution), the decision becomes pending.
+func (a *BayesianAgent) RecommendDecision(state iface.Stater) (iface.Actioner, Decision, error) {
+	action, decision, err := a.recommend(state, nil, true)
+	if err != nil {
+		return nil, Decision{}, err
+	}
+	a.track(decision, state, action)
+	return action, decision, nil
+}
+
+var _ DecisionLogger = (*JSONDecisionLogger)(nil)
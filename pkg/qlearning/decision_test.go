@@ -0,0 +1,160 @@
+package qlearning_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/ope"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DecisionLogging(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	actions, state, terminal := newRecommendFixture(mc, "X", "Y", "Z")
+
+	var log bytes.Buffer
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithExploration(qlearning.ConstantRate(.3)),
+		qlearning.WithDecisionLogger(qlearning.NewJSONDecisionLogger(&log)),
+		qlearning.WithSeed(1),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(state, actions["X"], terminal, 1)
+
+	action, decision, err := ba.RecommendDecision(state)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, decision.DecisionID, 32)
+	assert.Equal(t, "A", decision.StateID)
+	assert.Equal(t, []string{"X", "Y", "Z"}, decision.Actions)
+	assert.Equal(t, action.ID(), decision.ActionID)
+	if action.ID() == "X" {
+		assert.InDelta(t, .7+.1, decision.Propensity, 1e-12)
+	} else {
+		assert.InDelta(t, .1, decision.Propensity, 1e-12)
+	}
+
+	_, err = ba.RecommendAction(state)
+	assert.NoError(t, err)
+
+	var logged []qlearning.Decision
+	decoder := json.NewDecoder(&log)
+	for decoder.More() {
+		var d qlearning.Decision
+		if assert.NoError(t, decoder.Decode(&d)) {
+			logged = append(logged, d)
+		}
+	}
+	if assert.Len(t, logged, 2) {
+		assert.Equal(t, decision.DecisionID, logged[0].DecisionID)
+		assert.True(t, decision.Time.Equal(logged[0].Time))
+		assert.NotEqual(t, logged[0].DecisionID, logged[1].DecisionID)
+	}
+}
+
+func Test_DecisionLogReadableByOPE(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	_, state, _ := newRecommendFixture(mc, "X", "Y")
+
+	var log bytes.Buffer
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithDecisionLogger(qlearning.NewJSONDecisionLogger(&log)),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, decision, err := ba.RecommendDecision(state)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	records, err := ope.ReadRecords(&log)
+	if assert.NoError(t, err) && assert.Len(t, records, 1) {
+		assert.Equal(t, decision.ActionID, records[0].ActionID)
+		assert.Equal(t, .5, records[0].Propensity)
+	}
+}
+
+type failingLogger struct{}
+
+func (failingLogger) LogDecision(qlearning.Decision) error {
+	return fmt.Errorf("disk full")
+}
+
+func Test_DecisionLoggingFailure(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	_, state, _ := newRecommendFixture(mc, "X")
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithDecisionLogger(failingLogger{}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = ba.RecommendAction(state)
+	assert.Error(t, err)
+}
+
+func Test_DecisionPropensityWithFallback(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	actions, state, terminal := newRecommendFixture(mc, "A", "B")
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithExploration(qlearning.ConstantRate(.5)),
+		qlearning.WithConfidencePolicy(qlearning.ConfidencePolicy{
+			MinCalls: 5,
+			Fallback: func(iface.Stater) (iface.Actioner, error) { return actions["B"], nil },
+		}),
+		qlearning.WithSeed(3),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// A is best, but has been called too few times to be recommended, so B
+	// is recommended instead whenever the agent does not explore.
+	ba.Learn(state, actions["A"], terminal, 1)
+
+	const n = 4000
+	counts := map[string]float64{}
+	propensities := map[string]map[float64]bool{"A": {}, "B": {}}
+	for i := 0; i < n; i++ {
+		_, decision, err := ba.RecommendDecision(state)
+		if !assert.NoError(t, err) {
+			return
+		}
+		counts[decision.ActionID]++
+		propensities[decision.ActionID][decision.Propensity] = true
+	}
+
+	// Every decision for an action logs the same propensity, whether the
+	// action was explored or recommended by the fallback, and it matches how
+	// often the action is recommended.
+	expected := map[string]float64{"A": .25, "B": .75}
+	for actionID, p := range expected {
+		assert.Equal(t, map[float64]bool{p: true}, propensities[actionID], actionID)
+		assert.InDelta(t, p, counts[actionID]/n, .03, actionID)
+	}
+
+	ranked, err := ba.RankActions(state)
+	if assert.NoError(t, err) {
+		for _, action := range ranked {
+			assert.Equal(t, expected[action.ActionID], action.Probability)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package qlearning
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/drift"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// DriftSignal identifies the stream of values monitored for drift.
+type DriftSignal int
+
+const (
+	// RewardSignal monitors the rewards observed for each action.
+	RewardSignal DriftSignal = iota
+	// TDErrorSignal monitors the temporal difference error of each update,
+	// which is the difference between the target and the q-value from which
+	// the action was updated (see UpdateBaser).
+	TDErrorSignal
+)
+
+// DriftResponse determines how the agent responds to drift in an action.
+type DriftResponse int
+
+const (
+	// NotifyOnDrift only reports the drift.
+	NotifyOnDrift DriftResponse = iota
+	// ResetOnDrift forgets every observation of the action (see Resetter),
+	// so that its weighted q-value reverts to its prior. Stats that do not
+	// implement Resetter have their calls, and their evidence if they
+	// implement Forgetter, reset to zero; any window of recent rewards they
+	// keep is retained.
+	ResetOnDrift
+	// DownweightOnDrift retains only a fraction of the action's calls (and
+	// effective calls), so that its weighted q-value moves toward its prior.
+	DownweightOnDrift
+)
+
+// DriftEvent describes drift detected in the stream of an action.
+type DriftEvent struct {
+	StateID  string
+	ActionID string
+	// Step is the agent step at which drift was detected.
+	Step int
+	// Change is the direction of the change in the monitored stream.
+	Change drift.Change
+	// Value is the value that caused drift to be detected.
+	Value float64
+}
+
+// DriftMonitor configures the monitoring of each state/action's stream of
+// values for drift. See WithDriftMonitor.
+type DriftMonitor struct {
+	Signal DriftSignal
+	// NewDetector returns a new detector, and is called once for each
+	// state/action that is monitored.
+	NewDetector func() drift.Detector
+	// OnDrift, if not nil, is called each time drift is detected.
+	OnDrift  func(DriftEvent)
+	Response DriftResponse
+	// Retain is the fraction of calls retained by DownweightOnDrift.
+	Retain float64
+}
+
+func (m DriftMonitor) validate() error {
+	if m.NewDetector == nil {
+		return fmt.Errorf("drift monitor requires a detector")
+	}
+	if m.Response == DownweightOnDrift && !(m.Retain >= 0 && m.Retain <= 1) {
+		return fmt.Errorf("retained fraction must be between 0 and 1, got %v", m.Retain)
+	}
+	return nil
+}
+
+// Resetter is an optional interface that an ActionStatter may implement to
+// support ResetOnDrift. Reset must forget every observation of the action.
+type Resetter interface {
+	Reset()
+}
+
+// WithDriftMonitor makes the agent monitor each state/action for drift, and
+// respond as configured when drift is detected. The monitor (including the
+// state of its detectors) is not persisted with the agent's context. The
+// detector of an action is discarded when the action is pruned (see
+// PruneActions), or its state is evicted by a store that is an
+// EvictionNotifier.
+func WithDriftMonitor(monitor DriftMonitor) Option {
+	return func(a *BayesianAgent) error {
+		if err := monitor.validate(); err != nil {
+			return err
+		}
+		a.drift = &monitor
+		a.detectors = make(map[string]map[string]drift.Detector)
+		return nil
+	}
+}
+
+// monitorDrift feeds the monitored value of an update to the action's
+// detector, and responds to any drift detected.
+func (a *BayesianAgent) monitorDrift(state iface.Stater, action iface.Actioner, stats iface.ActionStatter, reward, tdError float64) {
+	if a.drift == nil {
+		return
+	}
+	value := reward
+	if a.drift.Signal == TDErrorSignal {
+		value = tdError
+	}
+
+	detectors, found := a.detectors[state.ID()]
+	if !found {
+		detectors = make(map[string]drift.Detector)
+		a.detectors[state.ID()] = detectors
+	}
+	detector, found := detectors[action.ID()]
+	if !found {
+		detector = a.drift.NewDetector()
+		detectors[action.ID()] = detector
+	}
+
+	change := detector.Add(value)
+	if change == drift.NoChange {
+		return
+	}
+	detector.Reset()
+
+	switch a.drift.Response {
+	case ResetOnDrift:
+		if r, ok := stats.(Resetter); ok {
+			r.Reset()
+		} else {
+			stats.SetCalls(0)
+			if f, ok := stats.(Forgetter); ok {
+				f.SetEvidence(Evidence{})
+			}
+		}
+	case DownweightOnDrift:
+		stats.SetCalls(int(math.Round(float64(stats.Calls()) * a.drift.Retain)))
+		if f, ok := stats.(Forgetter); ok {
+			e := f.Evidence()
+			e.Calls *= a.drift.Retain
+			f.SetEvidence(e)
+		}
+	}
+
+	if a.drift.OnDrift != nil {
+		a.drift.OnDrift(DriftEvent{
+			StateID:  state.ID(),
+			ActionID: action.ID(),
+			Step:     a.steps,
+			Change:   change,
+			Value:    value,
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package qlearning_test
+
+import (
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/drift"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPageHinkley() drift.Detector {
+	ph, err := drift.NewPageHinkley(.01, 2, 10)
+	if err != nil {
+		panic(err)
+	}
+	return ph
+}
+
+func Test_DriftMonitorReset(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	events := []qlearning.DriftEvent{}
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithDiscount(0),
+		qlearning.WithDriftMonitor(qlearning.DriftMonitor{
+			NewDetector: newPageHinkley,
+			OnDrift:     func(e qlearning.DriftEvent) { events = append(events, e) },
+			Response:    qlearning.ResetOnDrift,
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 0; i < 50; i++ {
+		ba.Learn(a, x, terminal, 1)
+	}
+	assert.Empty(t, events)
+
+	for i := 0; i < 10 && len(events) == 0; i++ {
+		ba.Learn(a, x, terminal, -1)
+	}
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "A", events[0].StateID)
+		assert.Equal(t, "X", events[0].ActionID)
+		assert.Equal(t, drift.Decrease, events[0].Change)
+		assert.Equal(t, -1.0, events[0].Value)
+	}
+
+	stats := ba.GetAgentContext().QValues["A"]["X"].(*qlearning.ActionStats)
+	assert.Equal(t, 0, stats.Calls())
+	assert.Equal(t, 0, stats.Rewards.Count)
+}
+
+// evidenceStats is an ActionStatter that implements Forgetter, but not
+// Resetter.
+type evidenceStats struct {
+	calls    int
+	raw      float64
+	weighted float64
+	evidence qlearning.Evidence
+}
+
+func (s *evidenceStats) Calls() int                       { return s.calls }
+func (s *evidenceStats) SetCalls(n int)                   { s.calls = n }
+func (s *evidenceStats) QValueRaw() float64               { return s.raw }
+func (s *evidenceStats) SetQValueRaw(v float64)           { s.raw = v }
+func (s *evidenceStats) QValueWeighted() float64          { return s.weighted }
+func (s *evidenceStats) SetQValueWeighted(v float64)      { s.weighted = v }
+func (s *evidenceStats) Evidence() qlearning.Evidence     { return s.evidence }
+func (s *evidenceStats) SetEvidence(e qlearning.Evidence) { s.evidence = e }
+func (s *evidenceStats) ObserveRecent(float64, int)       {}
+
+func Test_DriftMonitorResetWithoutResetter(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	stats := &evidenceStats{}
+	store := &recordingStore{}
+	store.UpdateStats(a, x, stats)
+	drifted := false
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithStore(store),
+		qlearning.WithDiscount(0),
+		qlearning.WithForgetting(qlearning.Forgetting{HalfLifeSteps: 1000}),
+		qlearning.WithDriftMonitor(qlearning.DriftMonitor{
+			NewDetector: newPageHinkley,
+			OnDrift:     func(qlearning.DriftEvent) { drifted = true },
+			Response:    qlearning.ResetOnDrift,
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 0; i < 50; i++ {
+		ba.Learn(a, x, terminal, 1)
+	}
+	for i := 0; i < 10 && !drifted; i++ {
+		ba.Learn(a, x, terminal, -1)
+	}
+	if assert.True(t, drifted) {
+		assert.Equal(t, 0, stats.Calls())
+		assert.Equal(t, 0.0, stats.evidence.Calls)
+	}
+}
+
+func Test_DriftMonitorDownweight(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithDriftMonitor(qlearning.DriftMonitor{
+			Signal:      qlearning.TDErrorSignal,
+			NewDetector: newPageHinkley,
+			Response:    qlearning.DownweightOnDrift,
+			Retain:      .5,
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	callsOf := func() int {
+		return ba.GetAgentContext().QValues["A"]["X"].Calls()
+	}
+
+	// The initial updates of X are themselves a change in the TD error, so
+	// the agent is allowed to settle before the steady state is measured.
+	for i := 0; i < 30; i++ {
+		ba.Learn(a, x, terminal, 1)
+	}
+	settled := callsOf()
+	for i := 0; i < 30; i++ {
+		ba.Learn(a, x, terminal, 1)
+	}
+	if !assert.Equal(t, settled+30, callsOf(), "no drift while rewards are steady") {
+		return
+	}
+
+	before := callsOf()
+	for i := 0; i < 10 && callsOf() >= before; i++ {
+		ba.Learn(a, x, terminal, -5)
+	}
+	assert.InDelta(t, float64(before)/2, callsOf(), 6)
+}
+
+func Test_DriftMonitorForgetsRemovedActions(t *testing.T) {
+	x := testAction("X")
+	a := &treeState{id: "A", actions: []iface.Actioner{x}}
+	b := &treeState{id: "B", actions: []iface.Actioner{x}}
+	c := &treeState{id: "C", actions: []iface.Actioner{x}}
+	terminal := &treeState{id: "terminal"}
+
+	store, err := qlearning.NewBoundedStore(qlearning.EvictionPolicy{MaxStates: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	detectors := 0
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithStore(store),
+		qlearning.WithDriftMonitor(qlearning.DriftMonitor{
+			NewDetector: func() drift.Detector {
+				detectors++
+				return newPageHinkley()
+			},
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Evicting A discards its detector, so A starts afresh when relearned.
+	ba.Learn(a, x, terminal, 1)
+	ba.Learn(b, x, terminal, 1)
+	ba.Learn(c, x, terminal, 1)
+	ba.Learn(a, x, terminal, 1)
+	assert.Equal(t, 4, detectors)
+
+	// So does pruning an action.
+	a.actions = nil
+	assert.Equal(t, []string{"X"}, ba.PruneActions(a, nil))
+	a.actions = []iface.Actioner{x}
+	ba.Learn(a, x, terminal, 1)
+	assert.Equal(t, 5, detectors)
+}
+
+func Test_WithDriftMonitorInvalid(t *testing.T) {
+	_, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithDriftMonitor(qlearning.DriftMonitor{}))
+	assert.Error(t, err)
+	_, err = qlearning.NewBayesianAgentWithOptions(qlearning.WithDriftMonitor(qlearning.DriftMonitor{
+		NewDetector: newPageHinkley,
+		Response:    qlearning.DownweightOnDrift,
+		Retain:      2,
+	}))
+	assert.Error(t, err)
+}
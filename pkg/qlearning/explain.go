@@ -0,0 +1,125 @@
+package qlearning
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	qlmath "github.com/eltorocorp/reinforcement-learning/pkg/qlearning/internal/math"
+)
+
+// Explanation describes how the agent would currently choose an action for a
+// state. An Explanation can be rendered as text via String, or as JSON via
+// encoding/json.
+type Explanation struct {
+	StateID          string
+	PrimingThreshold int
+	ExplorationRate  float64
+	// Actions describes every action of the state, ordered from greatest to
+	// least weighted q-value.
+	Actions []ActionExplanation
+	// Tied lists the IDs of the actions that share the greatest weighted
+	// q-value, in the order presented to the tie breaker.
+	Tied []string
+}
+
+// ActionExplanation describes how the weighted q-value of an action was
+// derived.
+type ActionExplanation struct {
+	ActionID string
+	Calls    int
+	// EffectiveCalls is the number of calls by which the action is weighted,
+	// which is less than Calls if the agent forgets (see Forgetting).
+	EffectiveCalls float64
+	QValueRaw      float64
+	// Prior is the estimate toward which the raw q-value was weighted (see
+	// Prior).
+	Prior float64
+	// PriorWeight is the share of the weighted q-value contributed by the
+	// prior under a Bayesian average, PrimingThreshold / (PrimingThreshold +
+	// EffectiveCalls). If the agent uses another Weighter, it is indicative
+	// only.
+	PriorWeight    float64
+	QValueWeighted float64
+	// Probability is the probability that RecommendAction would currently
+	// recommend the action (see RankedAction.Probability).
+	Probability float64
+}
+
+// Explain describes how the agent would currently choose an action for a
+// state: how the weighted q-value of each action was derived, which actions
+// are tied for the greatest value, and the probability with which each action
+// would be recommended, accounting for exploration and the agent's
+// ConfidencePolicy. Like RankActions, Explain does not choose an action, so
+// it does not consume any randomness or modify the agent's store, and calling
+// it does not alter the agent's subsequent choices.
+// An error is returned if the state has no actions, or if the fallback of the
+// agent's ConfidencePolicy returns an error.
+func (a *BayesianAgent) Explain(state iface.Stater) (Explanation, error) {
+	d, err := a.consider(state, nil)
+	if err != nil {
+		return Explanation{}, err
+	}
+	probabilities, err := a.probabilities(state, d)
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	e := Explanation{
+		StateID:          state.ID(),
+		PrimingThreshold: a.primingThreshold,
+		ExplorationRate:  d.rate,
+		Actions:          make([]ActionExplanation, len(d.actionIDs)),
+		Tied:             d.best,
+	}
+	for i, actionID := range d.actionIDs {
+		stats := d.actions[actionID]
+		calls := d.calls[actionID]
+		e.Actions[i] = ActionExplanation{
+			ActionID:       actionID,
+			Calls:          stats.Calls(),
+			EffectiveCalls: calls,
+			QValueRaw:      nanToZero(stats.QValueRaw()),
+			Prior:          nanToZero(d.priors[actionID]),
+			PriorWeight:    qlmath.SafeDivide(float64(a.primingThreshold), float64(a.primingThreshold)+calls),
+			QValueWeighted: d.weighted[actionID],
+			Probability:    probabilities[actionID],
+		}
+	}
+	sort.SliceStable(e.Actions, func(i, j int) bool {
+		return e.Actions[i].QValueWeighted > e.Actions[j].QValueWeighted
+	})
+	return e, nil
+}
+
+// String renders the explanation as a human readable table, in which the
+// actions tied for the greatest weighted q-value are marked with an asterisk.
+func (e Explanation) String() string {
+	var b bytes.Buffer
+	if len(e.Tied) == 1 {
+		fmt.Fprintf(&b, "state '%v': best action '%v'\n", e.StateID, e.Tied[0])
+	} else {
+		fmt.Fprintf(&b, "state '%v': %v actions tied for best\n", e.StateID, len(e.Tied))
+	}
+	fmt.Fprintf(&b, "priming threshold: %v, exploration rate: %.4g\n", e.PrimingThreshold, e.ExplorationRate)
+
+	tied := make(map[string]bool, len(e.Tied))
+	for _, actionID := range e.Tied {
+		tied[actionID] = true
+	}
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tCALLS\tRAW Q\tPRIOR\tPRIOR WEIGHT\tWEIGHTED Q\tPROBABILITY\t")
+	for _, action := range e.Actions {
+		marker := ""
+		if tied[action.ActionID] {
+			marker = " *"
+		}
+		fmt.Fprintf(w, "%v%v\t%v\t%.4g\t%.4g\t%.4g\t%.4g\t%.4g\t\n",
+			action.ActionID, marker, action.Calls, action.QValueRaw,
+			action.Prior, action.PriorWeight, action.QValueWeighted, action.Probability)
+	}
+	w.Flush()
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package qlearning_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Explain(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(1),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithPrior(qlearning.FixedPrior(2)),
+		qlearning.WithTieBreaker(func(int) int { return 0 }),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, x, terminal, 4)
+
+	e, err := ba.Explain(a)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, qlearning.Explanation{
+		StateID:          "A",
+		PrimingThreshold: 1,
+		Actions: []qlearning.ActionExplanation{
+			{ActionID: "X", Calls: 1, EffectiveCalls: 1, QValueRaw: 4, Prior: 2, PriorWeight: .5, QValueWeighted: 3, Probability: 1},
+			{ActionID: "Y", Calls: 0, QValueRaw: 0, Prior: 2, PriorWeight: 1, QValueWeighted: 2, Probability: 0},
+		},
+		Tied: []string{"X"},
+	}, e)
+
+	expectedText := "state 'A': best action 'X'\n" +
+		"priming threshold: 1, exploration rate: 0\n" +
+		"ACTION  CALLS  RAW Q  PRIOR  PRIOR WEIGHT  WEIGHTED Q  PROBABILITY  \n" +
+		"X *     1      4      2      0.5           3           1            \n" +
+		"Y       0      0      2      1             2           0            \n"
+	assert.Equal(t, expectedText, e.String())
+
+	encoded, err := json.Marshal(e)
+	if assert.NoError(t, err) {
+		var decoded qlearning.Explanation
+		assert.NoError(t, json.Unmarshal(encoded, &decoded))
+		assert.Equal(t, e, decoded)
+	}
+}
+
+func Test_ExplainTied(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	_, _, a, _, _ := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(1),
+		qlearning.WithExploration(qlearning.ConstantRate(.5)),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	e, err := ba.Explain(a)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"X", "Y"}, e.Tied)
+		assert.Equal(t, .5, e.ExplorationRate)
+		for _, action := range e.Actions {
+			assert.InDelta(t, .5, action.Probability, 1e-12)
+		}
+		assert.Contains(t, e.String(), "state 'A': 2 actions tied for best\n")
+	}
+}
+
+func Test_ExplainDoesNotConsumeRandomness(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	actions, state, terminal := newRecommendFixture(mc, "X", "Y", "Z")
+
+	recommendations := func(explain bool) []string {
+		ba, err := qlearning.NewBayesianAgentWithOptions(
+			qlearning.WithPrimingThreshold(0),
+			qlearning.WithLearningRate(1),
+			qlearning.WithDiscount(0),
+			qlearning.WithExploration(qlearning.ConstantRate(.5)),
+			qlearning.WithSeed(7),
+		)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		ba.Learn(state, actions["X"], terminal, 1)
+
+		var ids []string
+		for i := 0; i < 20; i++ {
+			if explain {
+				_, err := ba.Explain(state)
+				assert.NoError(t, err)
+			}
+			action, err := ba.RecommendAction(state)
+			if assert.NoError(t, err) {
+				ids = append(ids, action.ID())
+			}
+		}
+		return ids
+	}
+
+	assert.Equal(t, recommendations(false), recommendations(true))
+}
+
+func Test_ExplainNoActions(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	state := agent.NewMockStater(mc)
+	state.EXPECT().ID().Return("T").AnyTimes()
+	state.EXPECT().PossibleActions().Return([]iface.Actioner{}).AnyTimes()
+
+	_, err := qlearning.NewBayesianAgent(1, 1, 0).Explain(state)
+	assert.Error(t, err)
+}
@@ -0,0 +1,180 @@
+package qlearning
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Forgetting describes how quickly an agent forgets the evidence it has
+// gathered about each action, so that it can adapt when rewards drift.
+//
+// Rather than its call count, the agent weights each action by its effective
+// number of calls, which decays exponentially as steps or time pass without
+// the action being called. As the effective number of calls falls, the
+// action's weighted q-value returns toward its prior, and the priming effect
+// re-engages. If the agent's learning rate schedule depends on visits (see
+// VisitDecay), the effective number of calls is used, so that the raw q-value
+// also adapts more quickly after a period without observations.
+//
+// Forgetting requires stats that implement Forgetter, as ActionStats does.
+type Forgetting struct {
+	// HalfLifeSteps is the number of agent steps after which the evidence for
+	// an action that has not been called is halved. Zero disables decay over
+	// steps.
+	HalfLifeSteps float64
+	// HalfLife is the duration after which the evidence for an action that
+	// has not been called is halved. Zero disables decay over time.
+	HalfLife time.Duration
+	// Window is the number of most recent rewards summarized for each action
+	// (see ActionStats.RecentRewards). Zero disables the window.
+	Window int
+}
+
+func (f Forgetting) validate() error {
+	if !(f.HalfLifeSteps >= 0) || math.IsInf(f.HalfLifeSteps, 1) {
+		return fmt.Errorf("half life steps must not be negative, got %v", f.HalfLifeSteps)
+	}
+	if f.HalfLife < 0 {
+		return fmt.Errorf("half life must not be negative, got %v", f.HalfLife)
+	}
+	if f.Window < 0 {
+		return fmt.Errorf("window must not be negative, got %v", f.Window)
+	}
+	return nil
+}
+
+// retained returns the fraction of evidence gathered at e that remains at the
+// specified step and time.
+func (f Forgetting) retained(e Evidence, step int, now time.Time) float64 {
+	factor := 1.0
+	if f.HalfLifeSteps > 0 && step > e.Step {
+		factor *= math.Pow(.5, float64(step-e.Step)/f.HalfLifeSteps)
+	}
+	if f.HalfLife > 0 && e.Time != 0 {
+		if elapsed := now.UnixNano() - e.Time; elapsed > 0 {
+			factor *= math.Pow(.5, float64(elapsed)/float64(f.HalfLife))
+		}
+	}
+	return factor
+}
+
+// Evidence is the effective number of times an action has been called, as of
+// the step and time it was last called.
+type Evidence struct {
+	Calls float64
+	Step  int
+	// Time is the time of the last call, in nanoseconds since the Unix epoch.
+	Time int64
+}
+
+// Forgetter is an optional interface that an ActionStatter may implement to
+// support Forgetting.
+type Forgetter interface {
+	Evidence() Evidence
+	SetEvidence(Evidence)
+	// ObserveRecent records a reward in a window of the most recent size
+	// rewards.
+	ObserveRecent(reward float64, size int)
+}
+
+// WindowStats summarizes a sliding window of the most recent values of a
+// series. Once the window is full, each new value replaces the oldest.
+type WindowStats struct {
+	Size   int
+	Values []float64
+	// Next is the index of Values to be replaced by the next value once the
+	// window is full.
+	Next int
+}
+
+// Add includes value in the window, replacing the oldest value if the window
+// is full. If size differs from the window's current size, the window is
+// resized, retaining the most recent values.
+func (w *WindowStats) Add(value float64, size int) {
+	if size != w.Size {
+		w.resize(size)
+	}
+	if len(w.Values) < w.Size {
+		w.Values = append(w.Values, value)
+		return
+	}
+	w.Values[w.Next] = value
+	w.Next = (w.Next + 1) % w.Size
+}
+
+func (w *WindowStats) resize(size int) {
+	ordered := append(append([]float64(nil), w.Values[w.Next:]...), w.Values[:w.Next]...)
+	if len(ordered) > size {
+		ordered = ordered[len(ordered)-size:]
+	}
+	w.Size = size
+	w.Values = ordered
+	w.Next = 0
+}
+
+// Stats returns the count, mean, and variance of the values in the window.
+func (w WindowStats) Stats() RunningStats {
+	var rs RunningStats
+	for _, v := range w.Values {
+		rs.Add(v)
+	}
+	return rs
+}
+
+// WithForgetting makes the agent forget the evidence it has gathered about
+// each action over time (see Forgetting). Forgetting is not persisted with the
+// agent's context, though the evidence of each action is.
+func WithForgetting(forgetting Forgetting) Option {
+	return func(a *BayesianAgent) error {
+		if err := forgetting.validate(); err != nil {
+			return err
+		}
+		a.forgetting = &forgetting
+		return nil
+	}
+}
+
+// effectiveCalls returns the number of times an action has been called, as
+// discounted by the agent's forgetting.
+func (a *BayesianAgent) effectiveCalls(stats iface.ActionStatter, now time.Time) float64 {
+	if a.forgetting == nil {
+		return float64(stats.Calls())
+	}
+	f, ok := stats.(Forgetter)
+	if !ok {
+		return float64(stats.Calls())
+	}
+	e := f.Evidence()
+	if e.Calls == 0 {
+		return float64(stats.Calls())
+	}
+	return e.Calls * a.forgetting.retained(e, a.steps, now)
+}
+
+// remember records a call of an action with the agent's forgetting.
+func (a *BayesianAgent) remember(stats iface.ActionStatter, reward float64, now time.Time) {
+	f, ok := stats.(Forgetter)
+	if a.forgetting == nil || !ok {
+		return
+	}
+	f.SetEvidence(Evidence{
+		Calls: a.effectiveCalls(stats, now) + 1,
+		Step:  a.steps,
+		Time:  now.UnixNano(),
+	})
+	if a.forgetting.Window > 0 {
+		f.ObserveRecent(reward, a.forgetting.Window)
+	}
+}
+
+// now returns the current time according to the agent's clock (see
+// WithClock), or the zero time if the agent does not forget.
+func (a *BayesianAgent) now() time.Time {
+	if a.forgetting == nil {
+		return time.Time{}
+	}
+	return a.clock()
+}
@@ -0,0 +1,119 @@
+package qlearning_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ForgettingOverSteps(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, y, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(1),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithPrior(qlearning.FixedPrior(0)),
+		qlearning.WithForgetting(qlearning.Forgetting{HalfLifeSteps: 1}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ba.Learn(a, x, terminal, 4)
+	// X has been called once, and no steps have elapsed: (1*0 + 1*4) / 2.
+	assert.Equal(t, 2.0, weightedValues(ba, "A")["X"])
+
+	ba.Learn(a, y, terminal, 0)
+	// One step has elapsed since X was called, so its evidence has halved:
+	// (1*0 + .5*4) / 1.5.
+	assert.InDelta(t, 4.0/3, weightedValues(ba, "A")["X"], 1e-12)
+
+	stats := ba.GetAgentContext().QValues["A"]["X"].(*qlearning.ActionStats)
+	assert.Equal(t, 1, stats.Calls(), "call counts are not forgotten")
+	assert.Equal(t, 1.0, stats.Evidence().Calls)
+	assert.Equal(t, 0, stats.Evidence().Step)
+}
+
+func Test_ForgettingOverTime(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	now := time.Unix(1000, 0)
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(1),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithPrior(qlearning.FixedPrior(0)),
+		qlearning.WithClock(func() time.Time { return now }),
+		qlearning.WithForgetting(qlearning.Forgetting{HalfLife: time.Hour}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, x, terminal, 4)
+	ba.Learn(a, x, terminal, 4)
+
+	e, err := ba.Explain(a)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2.0, e.Actions[0].EffectiveCalls)
+	}
+
+	now = now.Add(2 * time.Hour)
+	e, err = ba.Explain(a)
+	if assert.NoError(t, err) {
+		assert.InDelta(t, .5, e.Actions[0].EffectiveCalls, 1e-12)
+		assert.InDelta(t, 2.0/3, e.Actions[0].PriorWeight, 1e-12)
+	}
+}
+
+func Test_ForgettingWindow(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithForgetting(qlearning.Forgetting{Window: 3}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, reward := range []float64{100, 1, 2, 3} {
+		ba.Learn(a, x, terminal, reward)
+	}
+
+	stats := ba.GetAgentContext().QValues["A"]["X"].(*qlearning.ActionStats)
+	recent := stats.RecentRewards()
+	assert.Equal(t, 3, recent.Count)
+	assert.Equal(t, 2.0, recent.Mean)
+	assert.Equal(t, 1.0, recent.Variance())
+	assert.Equal(t, 4, stats.Rewards.Count)
+}
+
+func Test_WindowStatsResize(t *testing.T) {
+	var w qlearning.WindowStats
+	for _, v := range []float64{1, 2, 3, 4} {
+		w.Add(v, 3)
+	}
+	w.Add(5, 2)
+	assert.ElementsMatch(t, []float64{4, 5}, w.Values)
+	w.Add(6, 2)
+	assert.Equal(t, 5.5, w.Stats().Mean)
+}
+
+func Test_WithForgettingInvalid(t *testing.T) {
+	for _, forgetting := range []qlearning.Forgetting{
+		{HalfLifeSteps: -1},
+		{HalfLife: -time.Second},
+		{Window: -1},
+	} {
+		_, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithForgetting(forgetting))
+		assert.Error(t, err, "%+v", forgetting)
+	}
+}
@@ -0,0 +1,108 @@
+package qlearning
+
+import (
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	qlmath "github.com/eltorocorp/reinforcement-learning/pkg/qlearning/internal/math"
+)
+
+// maxHierarchyDepth guards against cycles in a malformed hierarchy.
+const maxHierarchyDepth = 64
+
+// HierarchicalPrior returns a Prior for states that implement
+// iface.Parenter. The prior for an action of a state is its parent's value
+// for the same action, which is itself shrunk toward the grandparent's value
+// according to how many times the parent has observed the action, and so on up
+// the hierarchy. Thus a fine-grained state with little data backs off to the
+// pooled experience of its ancestors, rather than to the mean of its
+// siblings.
+//
+// threshold plays the role of the priming threshold at each ancestor: the
+// number of observations an ancestor requires before its own value is
+// trusted more than that of its parent. The prior of a state without a parent
+// (or of the root of a hierarchy) is supplied by base.
+//
+// Ancestors only accumulate experience if the agent pools each transition
+// into them. See WithHierarchy.
+//
+// If base is a StatsObserver, the hierarchical prior forwards every change to
+// the agent's stats to it.
+func HierarchicalPrior(threshold float64, base Prior) Prior {
+	return hierarchicalPrior{threshold: threshold, base: base}
+}
+
+type hierarchicalPrior struct {
+	threshold float64
+	base      Prior
+}
+
+func (p hierarchicalPrior) Estimate(store iface.QStorer, state iface.Stater, actionID string) float64 {
+	return p.estimate(store, state, actionID, 0)
+}
+
+func (p hierarchicalPrior) estimate(store iface.QStorer, state iface.Stater, actionID string, depth int) float64 {
+	parent := parentOf(state)
+	if parent == nil || depth >= maxHierarchyDepth {
+		return p.base.Estimate(store, state, actionID)
+	}
+	parentPrior := p.estimate(store, parent, actionID, depth+1)
+	stats, found := store.GetData()[parent.ID()][actionID]
+	if !found {
+		return parentPrior
+	}
+	return qlmath.BayesianAverage(
+		p.threshold,
+		float64(stats.Calls()),
+		nanToZero(parentPrior),
+		nanToZero(stats.QValueRaw()),
+	)
+}
+
+func (p hierarchicalPrior) ObserveStats(stateID, actionID string, stats iface.ActionStatter) {
+	if o, ok := p.base.(StatsObserver); ok {
+		o.ObserveStats(stateID, actionID, stats)
+	}
+}
+
+var _ StatsObserver = hierarchicalPrior{}
+
+// ancestors returns the parent, grandparent, etc. of a state.
+func ancestors(state iface.Stater) []iface.Stater {
+	result := []iface.Stater{}
+	for parent := parentOf(state); parent != nil && len(result) < maxHierarchyDepth; parent = parentOf(parent) {
+		result = append(result, parent)
+	}
+	return result
+}
+
+func parentOf(state iface.Stater) iface.Stater {
+	if p, ok := state.(iface.Parenter); ok {
+		return p.Parent()
+	}
+	return nil
+}
+
+// poolIntoAncestors applies the same update that was applied to a state and
+// action to each of the state's ancestors, so that each ancestor holds the
+// pooled experience of all of its descendants. Each ancestor's learning rate,
+// forgetting, and drift detector are handled as Learn handles the state's.
+//
+// Unlike Learn, which (under the default Bayesian average) updates an action
+// from its weighted q-value, each ancestor is updated from its raw q-value.
+// An ancestor's weighted q-value is only maintained if the agent is asked to
+// recommend an action for the ancestor itself, and HierarchicalPrior reads the
+// ancestor's raw q-value and shrinks it toward the grandparent's; updating
+// from a weighted value would thus either use a stale value or shrink the
+// ancestor's experience twice.
+func (a *BayesianAgent) poolIntoAncestors(state iface.Stater, action iface.Actioner, reward, bestValue float64, now time.Time) {
+	for _, ancestor := range ancestors(state) {
+		stats, found := a.store.GetStats(ancestor, action)
+		if !found {
+			stats = new(ActionStats)
+		}
+		a.updateStats(ancestor, action, stats, nanToZero(stats.QValueRaw()), reward, bestValue, now)
+		a.store.UpdateStats(ancestor, action, stats)
+		a.observeStats(ancestor.ID(), action.ID(), stats)
+	}
+}
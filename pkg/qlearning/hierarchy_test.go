@@ -0,0 +1,180 @@
+package qlearning_test
+
+import (
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/stretchr/testify/assert"
+)
+
+// treeState is an iface.Stater that implements iface.Parenter.
+type treeState struct {
+	id      string
+	parent  *treeState
+	actions []iface.Actioner
+}
+
+func (s *treeState) PossibleActions() []iface.Actioner      { return s.actions }
+func (s *treeState) ActionIsCompatible(iface.Actioner) bool { return true }
+func (s *treeState) ID() string                             { return s.id }
+func (s *treeState) Apply(iface.Actioner) error             { return nil }
+func (s *treeState) GetAction(id string) (iface.Actioner, error) {
+	return testAction(id), nil
+}
+func (s *treeState) Parent() iface.Stater {
+	if s.parent == nil {
+		return nil
+	}
+	return s.parent
+}
+
+func Test_WithHierarchy(t *testing.T) {
+	actions := []iface.Actioner{testAction("X"), testAction("Y")}
+	campaign := &treeState{id: "campaign", actions: actions}
+	group := &treeState{id: "group", parent: campaign, actions: actions}
+	busy := &treeState{id: "busy", parent: group, actions: actions}
+	quiet := &treeState{id: "quiet", parent: group, actions: actions}
+	terminal := &treeState{id: "terminal"}
+
+	a, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(2),
+		qlearning.WithDiscount(0),
+		qlearning.WithLearningRateSchedule(qlearning.VisitDecay(1, 1, 0)),
+		qlearning.WithHierarchy(0),
+		qlearning.WithTieBreaker(func(int) int { return 0 }),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 0; i < 10; i++ {
+		a.Learn(busy, testAction("X"), terminal, 1)
+		a.Learn(busy, testAction("Y"), terminal, 5)
+	}
+
+	// Every ancestor has pooled the experience of the busy placement.
+	c := a.GetAgentContext()
+	for _, id := range []string{"group", "campaign"} {
+		assert.Equal(t, 10, c.QValues[id]["Y"].Calls())
+		assert.Equal(t, 5.0, c.QValues[id]["Y"].QValueRaw())
+	}
+
+	// The quiet placement has never been observed, so its actions back off
+	// entirely to the pooled values of its ad group, rather than to the mean
+	// of its siblings (which would make X and Y indistinguishable).
+	action, err := a.RecommendAction(quiet)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Y", action.ID())
+	}
+	ranked, err := a.RankActions(quiet)
+	if assert.NoError(t, err) && assert.Len(t, ranked, 2) {
+		assert.Equal(t, 5.0, ranked[0].QValueWeighted)
+		assert.Equal(t, 1.0, ranked[1].QValueWeighted)
+	}
+}
+
+func Test_HierarchicalPriorShrinksTowardAncestors(t *testing.T) {
+	actions := []iface.Actioner{testAction("X")}
+	root := &treeState{id: "root", actions: actions}
+	child := &treeState{id: "child", parent: root, actions: actions}
+	leaf := &treeState{id: "leaf", parent: child, actions: actions}
+
+	store := &recordingStore{}
+	store.UpdateStats(root, testAction("X"), &qlearning.ActionStats{CallCount: 4, QRaw: 10})
+	store.UpdateStats(child, testAction("X"), &qlearning.ActionStats{CallCount: 1, QRaw: 0})
+
+	// The root is shrunk toward the base prior: (4*-2 + 4*10) / (4 + 4)
+	// The child is shrunk toward the root: (4*4 + 1*0) / (4 + 1)
+	prior := qlearning.HierarchicalPrior(4, qlearning.FixedPrior(-2))
+	assert.Equal(t, -2.0, prior.Estimate(store, root, "X"))
+	assert.Equal(t, 4.0, prior.Estimate(store, child, "X"))
+	assert.Equal(t, 3.2, prior.Estimate(store, leaf, "X"))
+	assert.Equal(t, -2.0, prior.Estimate(store, leaf, "Z"))
+}
+
+func Test_WithHierarchyValidation(t *testing.T) {
+	_, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithHierarchy(-1))
+	assert.EqualError(t, err, "hierarchy threshold must not be negative, got -1")
+}
+
+func Test_WithHierarchyIndependentOfPriorOrder(t *testing.T) {
+	actions := []iface.Actioner{testAction("X"), testAction("Y")}
+	group := &treeState{id: "group", actions: actions}
+	busy := &treeState{id: "busy", parent: group, actions: actions}
+	quiet := &treeState{id: "quiet", parent: group, actions: actions}
+	terminal := &treeState{id: "terminal"}
+
+	orders := map[string][]qlearning.Option{
+		"prior first":     {qlearning.WithPrior(qlearning.FixedPrior(-2)), qlearning.WithHierarchy(0)},
+		"hierarchy first": {qlearning.WithHierarchy(0), qlearning.WithPrior(qlearning.FixedPrior(-2))},
+	}
+	for name, opts := range orders {
+		t.Run(name, func(t *testing.T) {
+			a, err := qlearning.NewBayesianAgentWithOptions(append([]qlearning.Option{
+				qlearning.WithPrimingThreshold(2),
+				qlearning.WithDiscount(0),
+				qlearning.WithLearningRateSchedule(qlearning.VisitDecay(1, 1, 0)),
+			}, opts...)...)
+			if !assert.NoError(t, err) {
+				return
+			}
+			for i := 0; i < 10; i++ {
+				a.Learn(busy, testAction("X"), terminal, 3)
+			}
+
+			// X backs off to the group, and Y (never observed) to the
+			// fixed prior at the root of the hierarchy.
+			ranked, err := a.RankActions(quiet)
+			if assert.NoError(t, err) && assert.Len(t, ranked, 2) {
+				assert.Equal(t, qlearning.RankedAction{ActionID: "X", QValueWeighted: 3, Probability: 1}, ranked[0])
+				assert.Equal(t, qlearning.RankedAction{ActionID: "Y", QValueWeighted: -2}, ranked[1])
+			}
+		})
+	}
+}
+
+func Test_WithHierarchyForgetsAndMonitorsAncestors(t *testing.T) {
+	actions := []iface.Actioner{testAction("X")}
+	parent := &treeState{id: "parent", actions: actions}
+	leaf := &treeState{id: "leaf", parent: parent, actions: actions}
+	terminal := &treeState{id: "terminal"}
+
+	events := []qlearning.DriftEvent{}
+	a, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithDiscount(0),
+		qlearning.WithLearningRateSchedule(qlearning.VisitDecay(1, 1, 0)),
+		qlearning.WithForgetting(qlearning.Forgetting{HalfLifeSteps: 4}),
+		qlearning.WithDriftMonitor(qlearning.DriftMonitor{
+			NewDetector: newPageHinkley,
+			OnDrift:     func(e qlearning.DriftEvent) { events = append(events, e) },
+			Response:    qlearning.ResetOnDrift,
+		}),
+		qlearning.WithHierarchy(0),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 0; i < 50; i++ {
+		a.Learn(leaf, testAction("X"), terminal, 1)
+	}
+	for i := 0; i < 10 && len(events) < 2; i++ {
+		a.Learn(leaf, testAction("X"), terminal, -1)
+	}
+
+	// The parent only learns from the leaf, so it forgets, and detects the
+	// drift, exactly as the leaf does.
+	stateIDs := []string{}
+	for _, e := range events {
+		stateIDs = append(stateIDs, e.StateID)
+	}
+	assert.ElementsMatch(t, []string{"parent", "leaf"}, stateIDs)
+	c := a.GetAgentContext()
+	parentStats := c.QValues["parent"]["X"].(*qlearning.ActionStats)
+	leafStats := c.QValues["leaf"]["X"].(*qlearning.ActionStats)
+	assert.Equal(t, leafStats.Evidence(), parentStats.Evidence())
+	assert.Equal(t, leafStats.Calls(), parentStats.Calls())
+	assert.Equal(t, leafStats.QValueRaw(), parentStats.QValueRaw())
+}
@@ -60,3 +60,70 @@ type ActionStatter interface {
 	QValueWeighted() float64
 	SetQValueWeighted(float64)
 }
+
+// Featurer is an optional interface that a Stater may implement to describe
+// itself as a vector of numeric features. Agents that generalise across states
+// (rather than treating each state ID independently) rely on this interface.
+// Implementers should take care to ensure the length and ordering of the
+// features are consistent for every state of a given model.
+type Featurer interface {
+	Features() []float64
+}
+
+// Terminaler is an optional interface that a Stater may implement to indicate
+// that it ends an episode, and thus has no future rewards.
+type Terminaler interface {
+	IsTerminal() bool
+}
+
+// QStorer is anything that can store the stats of each action that has been
+// applied to each state.
+type QStorer interface {
+	// GetStats returns the stats for a given state and action. If the action
+	// has not been recorded for the state, GetStats returns nil, false.
+	GetStats(Stater, Actioner) (ActionStatter, bool)
+
+	// UpdateStats updates the stats of a given state and action.
+	UpdateStats(Stater, Actioner, ActionStatter)
+
+	// GetActionsForState returns the stats of each action recorded for a
+	// given state, keyed by action ID. Reading a state must not add it to
+	// the store, so nil may be returned for a state with no recorded actions.
+	GetActionsForState(Stater) map[string]ActionStatter
+
+	// GetData returns the stats of every action recorded for every state,
+	// keyed by state ID and then by action ID.
+	GetData() map[string]map[string]ActionStatter
+
+	// SetData replaces every recorded stat with the supplied data.
+	SetData(map[string]map[string]ActionStatter)
+}
+
+// Parenter is an optional interface that a Stater may implement to indicate
+// that it is part of a hierarchy of states (for example, campaign, ad group,
+// and placement). Parent returns the state's parent, or nil if the state is
+// at the root of the hierarchy.
+type Parenter interface {
+	Parent() Stater
+}
+
+// VarianceStatter is an optional interface that an ActionStatter may
+// implement to report the sample variance of the values observed for an
+// action.
+type VarianceStatter interface {
+	Variance() float64
+}
+
+// Observer is an optional interface that an ActionStatter may implement to
+// accumulate every reward observed for an action, along with the temporal
+// difference target (reward plus discounted future value) derived from it.
+type Observer interface {
+	Observe(reward, target float64)
+}
+
+// StatsDeleter is an optional interface that a QStorer may implement to
+// remove the stats of an action from a state. Stores that do not implement it
+// are assumed to return a live map from GetActionsForState.
+type StatsDeleter interface {
+	DeleteStats(state Stater, actionID string)
+}
@@ -18,20 +18,41 @@ func NewQMap() *QMap {
 // If a the specified action has not been recorded for the given state, the
 // method will return nil, false.
 func (qq *QMap) GetStats(state iface.Stater, action iface.Actioner) (stats iface.ActionStatter, found bool) {
-	actions := qq.GetActionsForState(state)
-	stats, found = actions[action.ID()]
+	stats, found = qq.Data[state.ID()][action.ID()]
 	return
 }
 
-// UpdateStats updates the stats of a given state and action.
+// UpdateStats updates the stats of a given state and action, adding the
+// state to the map if necessary.
 func (qq *QMap) UpdateStats(state iface.Stater, action iface.Actioner, stats iface.ActionStatter) {
-	qq.GetActionsForState(state)[action.ID()] = stats
-}
-
-// GetActionsForState returns the actions associated with a given state.
-func (qq *QMap) GetActionsForState(state iface.Stater) map[string]iface.ActionStatter {
 	if _, exists := qq.Data[state.ID()]; !exists {
 		qq.Data[state.ID()] = make(map[string]iface.ActionStatter)
 	}
+	qq.Data[state.ID()][action.ID()] = stats
+}
+
+// GetActionsForState returns the actions associated with a given state, or
+// nil if no actions have been recorded for the state.
+func (qq *QMap) GetActionsForState(state iface.Stater) map[string]iface.ActionStatter {
 	return qq.Data[state.ID()]
 }
+
+// GetData returns the stats of every action recorded for every state.
+func (qq *QMap) GetData() map[string]map[string]iface.ActionStatter {
+	return qq.Data
+}
+
+// SetData replaces every recorded stat with the supplied data.
+func (qq *QMap) SetData(data map[string]map[string]iface.ActionStatter) {
+	qq.Data = data
+}
+
+// DeleteStats removes the stats for a given state and action, if any.
+func (qq *QMap) DeleteStats(state iface.Stater, actionID string) {
+	delete(qq.Data[state.ID()], actionID)
+}
+
+var (
+	_ iface.QStorer      = (*QMap)(nil)
+	_ iface.StatsDeleter = (*QMap)(nil)
+)
@@ -24,6 +24,8 @@ func Test_GetStats_NoData(t *testing.T) {
 
 	assert.Equal(t, false, found)
 	assert.Equal(t, nil, stats)
+	assert.Nil(t, qq.GetActionsForState(state))
+	assert.Empty(t, qq.GetData(), "reading a state does not add it")
 }
 
 func Test_GetStats_StateHasData(t *testing.T) {
@@ -45,3 +47,21 @@ func Test_GetStats_StateHasData(t *testing.T) {
 	assert.Equal(t, true, found)
 	assert.Equal(t, stats, actStats)
 }
+
+func Test_DeleteStats(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	state := agent.NewMockStater(mc)
+	state.EXPECT().ID().Return("A").AnyTimes()
+
+	action := agent.NewMockActioner(mc)
+	action.EXPECT().ID().Return("X").AnyTimes()
+
+	qq := datastructures.NewQMap()
+	qq.UpdateStats(state, action, agent.NewMockActionStatter(mc))
+	qq.DeleteStats(state, "X")
+	_, found := qq.GetStats(state, action)
+
+	assert.Equal(t, false, found)
+}
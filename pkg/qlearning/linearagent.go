@@ -0,0 +1,300 @@
+package qlearning
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// LinearAgent is a q-learning agent that approximates the q-value of each
+// action as a linear function of a state's features, such that
+// Q(s,a) = w_a · φ(s), where φ(s) is supplied by the state's Features method
+// (see iface.Featurer).
+//
+// Unlike the BayesianAgent, which learns the value of each state
+// independently, the LinearAgent generalises what it has learned to states it
+// has never observed, provided that those states have similar features.
+//
+// The weights are learned via semi-gradient q-learning with optional L2
+// regularisation.
+// see http://incompleteideas.net/book/RLbook2020.pdf (Section 10.1)
+type LinearAgent struct {
+	TieBreaker     func(int) int
+	rng            *rand.Rand
+	weights        map[string][]float64
+	dimensions     int
+	learningRate   Schedule
+	discountFactor float64
+	regularization float64
+	steps          int
+}
+
+// LinearOption configures a LinearAgent. A LinearOption returns an error if
+// the value it is configured with is invalid.
+type LinearOption func(*LinearAgent) error
+
+// WithLinearSeed seeds the random source from which the LinearAgent breaks
+// ties, making its recommendations reproducible. By default, the source is
+// seeded from the current time.
+func WithLinearSeed(seed int64) LinearOption {
+	return func(a *LinearAgent) error {
+		a.rng = rand.New(NewSource(seed))
+		return nil
+	}
+}
+
+// NewLinearAgent returns a reference to a new LinearAgent configured by the
+// supplied options.
+//
+// dimensions:
+//
+//	The length of the feature vectors supplied by each state.
+//
+// learningRate:
+//
+//	The schedule that determines the learning rate applied at each step.
+//	See ConstantRate, InverseTimeDecay, and ExponentialDecay. VisitDecay is
+//	not supported, since the agent does not count visits to each state.
+//
+// discountFactor:
+//
+//	From wikipedia: The discount factor determines the importance of future
+//	rewards.
+//	see: https://en.wikipedia.org/wiki/Q-learning#Discount_factor
+//
+// regularization:
+//
+//	The L2 penalty applied to the weights at each step. Zero disables
+//	regularisation.
+//
+// An error is returned if dimensions is less than one, if the learning rate
+// schedule is invalid, if discountFactor is not between 0 and 1 inclusive, if
+// regularization is negative, or if any option is invalid.
+func NewLinearAgent(dimensions int, learningRate Schedule, discountFactor, regularization float64, opts ...LinearOption) (*LinearAgent, error) {
+	if err := validateLinear(dimensions, learningRate, discountFactor, regularization); err != nil {
+		return nil, err
+	}
+	a := &LinearAgent{
+		weights:        make(map[string][]float64),
+		dimensions:     dimensions,
+		learningRate:   learningRate,
+		discountFactor: discountFactor,
+		regularization: regularization,
+		rng:            rand.New(NewSource(time.Now().UnixNano())),
+	}
+	a.TieBreaker = func(n int) int {
+		return a.rng.Intn(n)
+	}
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// validateLinear returns an error if a LinearAgent can not be configured with
+// the specified dimensions, learning rate schedule, discount factor, and
+// regularization.
+func validateLinear(dimensions int, learningRate Schedule, discountFactor, regularization float64) error {
+	if dimensions < 1 {
+		return fmt.Errorf("dimensions must be at least 1, got %v", dimensions)
+	}
+	if err := learningRate.validate(); err != nil {
+		return err
+	}
+	if learningRate.Kind == VisitDecaySchedule {
+		return fmt.Errorf("linear agent does not support the '%v' learning rate schedule", learningRate.Kind)
+	}
+	if !(discountFactor >= 0 && discountFactor <= 1) {
+		return fmt.Errorf("discount factor must be between 0 and 1, got %v", discountFactor)
+	}
+	if !(regularization >= 0) {
+		return fmt.Errorf("regularization must not be negative, got %v", regularization)
+	}
+	return nil
+}
+
+// Learn updates the weights of actionTaken according to a transition that has
+// occured from a previous state through some action to a current state. If
+// currentState implements iface.Terminaler and reports that it is terminal, no
+// future reward is expected from it. If no action has been previously taken,
+// or there is no previous state, Learn is a no-op. Learn will panic if
+// currentState is nil, or if either state does not supply features of the
+// expected dimensions.
+func (a *LinearAgent) Learn(previousState iface.Stater, actionTaken iface.Actioner, currentState iface.Stater, reward float64) {
+	if previousState == nil || actionTaken == nil {
+		return
+	}
+
+	if currentState == nil {
+		panic("currentState must not be nil")
+	}
+
+	previousFeatures, err := a.getFeatures(previousState)
+	if err != nil {
+		panic(err)
+	}
+	currentFeatures, err := a.getFeatures(currentState)
+	if err != nil {
+		panic(err)
+	}
+
+	bestNext := 0.0
+	terminal := false
+	if t, ok := currentState.(iface.Terminaler); ok {
+		terminal = t.IsTerminal()
+	}
+	if !terminal {
+		for i, action := range currentState.PossibleActions() {
+			q := dot(a.getWeights(action.ID()), currentFeatures)
+			if i == 0 || q > bestNext {
+				bestNext = q
+			}
+		}
+	}
+
+	w, found := a.weights[actionTaken.ID()]
+	if !found {
+		w = make([]float64, a.dimensions)
+		a.weights[actionTaken.ID()] = w
+	}
+	tdError := reward + a.discountFactor*bestNext - dot(w, previousFeatures)
+	rate := a.learningRate.Rate(a.steps)
+	for i := range w {
+		w[i] += rate * (tdError*previousFeatures[i] - a.regularization*w[i])
+	}
+	a.steps++
+}
+
+// Transition applies an action to a given state.
+func (a *LinearAgent) Transition(currentState iface.Stater, action iface.Actioner) error {
+	if !currentState.ActionIsCompatible(action) {
+		return fmt.Errorf("action %v is not compatible with state %v", action.ID(), currentState.ID())
+	}
+	return currentState.Apply(action)
+}
+
+// RecommendAction recommends the action with the greatest approximate q-value
+// for the given state. If the q-value for two or more actions are the same,
+// the action is chosen at random.
+func (a *LinearAgent) RecommendAction(state iface.Stater) (iface.Actioner, error) {
+	features, err := a.getFeatures(state)
+	if err != nil {
+		return nil, err
+	}
+
+	bestActions := []iface.Actioner{}
+	bestValue := -1 * math.MaxFloat64
+	for _, action := range state.PossibleActions() {
+		value := dot(a.getWeights(action.ID()), features)
+		if value > bestValue {
+			bestActions = []iface.Actioner{action}
+			bestValue = value
+		} else if value == bestValue {
+			bestActions = append(bestActions, action)
+		}
+	}
+
+	if len(bestActions) == 0 {
+		return nil, fmt.Errorf("state '%v' reports no possible actions", state.ID())
+	}
+	return bestActions[a.TieBreaker(len(bestActions))], nil
+}
+
+// QValue returns the approximate q-value of taking an action from a state.
+func (a *LinearAgent) QValue(state iface.Stater, action iface.Actioner) (float64, error) {
+	features, err := a.getFeatures(state)
+	if err != nil {
+		return 0, err
+	}
+	return dot(a.getWeights(action.ID()), features), nil
+}
+
+func (a *LinearAgent) getFeatures(state iface.Stater) ([]float64, error) {
+	featurer, ok := state.(iface.Featurer)
+	if !ok {
+		return nil, fmt.Errorf("state '%v' does not provide features", state.ID())
+	}
+	features := featurer.Features()
+	if len(features) != a.dimensions {
+		return nil, fmt.Errorf("state '%v' provided %v features, expected %v", state.ID(), len(features), a.dimensions)
+	}
+	return features, nil
+}
+
+// getWeights returns the weights of an action, or nil if the action has not
+// been learned, in which case its q-value is zero. The returned weights must
+// not be modified.
+func (a *LinearAgent) getWeights(actionID string) []float64 {
+	return a.weights[actionID]
+}
+
+// dot returns the dot product of u and v. u may be nil, in which case the
+// product is zero.
+func dot(u, v []float64) (sum float64) {
+	for i := range u {
+		sum += u[i] * v[i]
+	}
+	return
+}
+
+// LinearAgentContext provides information about the internal conditions of a
+// LinearAgent.
+type LinearAgentContext struct {
+	LearningRate   Schedule
+	DiscountFactor float64
+	Regularization float64
+	Dimensions     int
+	Steps          int
+	Weights        map[string][]float64
+}
+
+// GetAgentContext provides information about the internal conditions of the
+// Agent. It is intended to allow the weights of the Agent to be serialized
+// without exposing fields that should remain private.
+func (a *LinearAgent) GetAgentContext() LinearAgentContext {
+	weights := make(map[string][]float64, len(a.weights))
+	for id, w := range a.weights {
+		weights[id] = append([]float64(nil), w...)
+	}
+	return LinearAgentContext{
+		LearningRate:   a.learningRate,
+		DiscountFactor: a.discountFactor,
+		Regularization: a.regularization,
+		Dimensions:     a.dimensions,
+		Steps:          a.steps,
+		Weights:        weights,
+	}
+}
+
+// SetAgentContext sets the internal conditions of the Agent based on a
+// pre-existing LinearAgentContext. An error is returned if the context's
+// Dimensions, learning rate schedule, DiscountFactor, or Regularization would
+// be rejected by NewLinearAgent, or if the weights of any action are
+// inconsistent with the context's Dimensions. The agent is left unchanged if
+// an error is returned.
+func (a *LinearAgent) SetAgentContext(c LinearAgentContext) error {
+	if err := validateLinear(c.Dimensions, c.LearningRate, c.DiscountFactor, c.Regularization); err != nil {
+		return err
+	}
+	weights := make(map[string][]float64, len(c.Weights))
+	for id, w := range c.Weights {
+		if len(w) != c.Dimensions {
+			return fmt.Errorf("weights for action '%v' have %v dimensions, expected %v", id, len(w), c.Dimensions)
+		}
+		weights[id] = append([]float64(nil), w...)
+	}
+	a.learningRate = c.LearningRate
+	a.discountFactor = c.DiscountFactor
+	a.regularization = c.Regularization
+	a.dimensions = c.Dimensions
+	a.steps = c.Steps
+	a.weights = weights
+	return nil
+}
+
+var _ iface.Agenter = (*LinearAgent)(nil)
@@ -0,0 +1,268 @@
+package qlearning_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type testAction string
+
+func (a testAction) ID() string { return string(a) }
+
+// featureState is a minimal iface.Stater that also implements iface.Featurer.
+type featureState struct {
+	features []float64
+	actions  []iface.Actioner
+}
+
+func (s *featureState) PossibleActions() []iface.Actioner      { return s.actions }
+func (s *featureState) ActionIsCompatible(iface.Actioner) bool { return true }
+func (s *featureState) ID() string                             { return fmt.Sprint(s.features) }
+func (s *featureState) Apply(iface.Actioner) error             { return nil }
+func (s *featureState) Features() []float64                    { return s.features }
+func (s *featureState) GetAction(id string) (iface.Actioner, error) {
+	return testAction(id), nil
+}
+
+func Test_LinearAgentLearn(t *testing.T) {
+	a, err := qlearning.NewLinearAgent(2, qlearning.ConstantRate(.5), 0, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	actions := []iface.Actioner{testAction("X"), testAction("Y")}
+	previous := &featureState{[]float64{1, 2}, actions}
+	current := &featureState{[]float64{0, 1}, actions}
+
+	a.Learn(nil, testAction("X"), current, 1)
+	a.Learn(previous, testAction("X"), current, 1)
+
+	c := a.GetAgentContext()
+	assert.Equal(t, 1, c.Steps)
+	assert.Equal(t, []float64{.5, 1}, c.Weights["X"])
+	assert.NotContains(t, c.Weights, "Y")
+}
+
+func Test_LinearAgentRegularization(t *testing.T) {
+	a, err := qlearning.NewLinearAgent(1, qlearning.ConstantRate(.5), 0, .5)
+	if !assert.NoError(t, err) {
+		return
+	}
+	state := &featureState{[]float64{1}, []iface.Actioner{testAction("X")}}
+	if err := a.SetAgentContext(qlearning.LinearAgentContext{
+		LearningRate:   qlearning.ConstantRate(.5),
+		Regularization: .5,
+		Dimensions:     1,
+		Weights:        map[string][]float64{"X": {2}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// td error = 2 - 2 = 0, so only the L2 penalty applies: 2 - .5*.5*2
+	a.Learn(state, testAction("X"), state, 2)
+	q, err := a.QValue(state, testAction("X"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, q)
+}
+
+func Test_LinearAgentRecommendAction(t *testing.T) {
+	a, err := qlearning.NewLinearAgent(2, qlearning.ConstantRate(.1), .5, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	actions := []iface.Actioner{testAction("X"), testAction("Y")}
+	left := &featureState{[]float64{1, 0}, actions}
+	right := &featureState{[]float64{0, 1}, actions}
+	for i := 0; i < 200; i++ {
+		a.Learn(left, testAction("X"), right, 1)
+		a.Learn(left, testAction("Y"), right, 0)
+		a.Learn(right, testAction("X"), left, 0)
+		a.Learn(right, testAction("Y"), left, 1)
+	}
+
+	action, err := a.RecommendAction(left)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "X", action.ID())
+	}
+	action, err = a.RecommendAction(right)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Y", action.ID())
+	}
+
+	// The agent generalises to states it has never observed.
+	action, err = a.RecommendAction(&featureState{[]float64{.9, .2}, actions})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "X", action.ID())
+	}
+}
+
+func Test_LinearAgentRecommendActionErrors(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	plainState := agent.NewMockStater(mc)
+	plainState.EXPECT().ID().Return("A").AnyTimes()
+
+	a, err := qlearning.NewLinearAgent(2, qlearning.ConstantRate(.1), .5, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = a.RecommendAction(plainState)
+	assert.EqualError(t, err, "state 'A' does not provide features")
+
+	_, err = a.RecommendAction(&featureState{[]float64{1}, nil})
+	assert.EqualError(t, err, "state '[1]' provided 1 features, expected 2")
+
+	_, err = a.RecommendAction(&featureState{[]float64{1, 1}, nil})
+	assert.EqualError(t, err, "state '[1 1]' reports no possible actions")
+}
+
+func Test_LinearAgentContextRoundTrip(t *testing.T) {
+	original, err := qlearning.NewLinearAgent(2, qlearning.InverseTimeDecay(1, .1, .01), .9, .001)
+	if !assert.NoError(t, err) {
+		return
+	}
+	actions := []iface.Actioner{testAction("X"), testAction("Y")}
+	state := &featureState{[]float64{1, 2}, actions}
+	original.Learn(state, testAction("X"), state, 1)
+
+	data, err := json.Marshal(original.GetAgentContext())
+	if !assert.NoError(t, err) {
+		return
+	}
+	var c qlearning.LinearAgentContext
+	if !assert.NoError(t, json.Unmarshal(data, &c)) {
+		return
+	}
+	restored, err := qlearning.NewLinearAgent(1, qlearning.ConstantRate(1), 0, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.NoError(t, restored.SetAgentContext(c)) {
+		assert.Equal(t, original.GetAgentContext(), restored.GetAgentContext())
+	}
+
+	c.Weights["X"] = []float64{1}
+	assert.EqualError(t, restored.SetAgentContext(c), "weights for action 'X' have 1 dimensions, expected 2")
+}
+
+// terminalFeatureState is a featureState that reports whether it is terminal.
+type terminalFeatureState struct {
+	featureState
+	terminal bool
+}
+
+func (s *terminalFeatureState) IsTerminal() bool { return s.terminal }
+
+func Test_LinearAgentLearnTerminal(t *testing.T) {
+	a, err := qlearning.NewLinearAgent(1, qlearning.ConstantRate(1), 1, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	actions := []iface.Actioner{testAction("X")}
+	if !assert.NoError(t, a.SetAgentContext(qlearning.LinearAgentContext{
+		LearningRate:   qlearning.ConstantRate(1),
+		DiscountFactor: 1,
+		Dimensions:     1,
+		Weights:        map[string][]float64{"X": {2}},
+	})) {
+		return
+	}
+	previous := &featureState{[]float64{1}, actions}
+
+	// The next state is worth 2, which is only added to the target while it
+	// is not terminal: 2 + 1*(1 + 1*2 - 2), then 3 + 1*(1 - 3).
+	a.Learn(previous, testAction("X"), &terminalFeatureState{featureState{[]float64{1}, actions}, false}, 1)
+	assert.Equal(t, []float64{3}, a.GetAgentContext().Weights["X"])
+	a.Learn(previous, testAction("X"), &terminalFeatureState{featureState{[]float64{1}, actions}, true}, 1)
+	assert.Equal(t, []float64{1}, a.GetAgentContext().Weights["X"])
+}
+
+func Test_LinearAgentReadsDoNotAddWeights(t *testing.T) {
+	a, err := qlearning.NewLinearAgent(1, qlearning.ConstantRate(1), 1, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	actions := []iface.Actioner{testAction("X"), testAction("Y")}
+	state := &featureState{[]float64{1}, actions}
+
+	_, err = a.RecommendAction(state)
+	assert.NoError(t, err)
+	_, err = a.QValue(state, testAction("Y"))
+	assert.NoError(t, err)
+	assert.Empty(t, a.GetAgentContext().Weights)
+
+	a.Learn(state, testAction("X"), state, 1)
+	weights := a.GetAgentContext().Weights
+	assert.Len(t, weights, 1)
+	assert.Equal(t, []float64{1}, weights["X"])
+}
+
+func Test_NewLinearAgentValidation(t *testing.T) {
+	_, err := qlearning.NewLinearAgent(1, qlearning.VisitDecay(1, .8, 0), 0, 0)
+	assert.EqualError(t, err, "linear agent does not support the 'visit-decay' learning rate schedule")
+
+	_, err = qlearning.NewLinearAgent(1, qlearning.ConstantRate(0), 0, 0)
+	assert.EqualError(t, err, "initial learning rate must be positive and finite, got 0")
+
+	_, err = qlearning.NewLinearAgent(0, qlearning.ConstantRate(1), 0, 0)
+	assert.EqualError(t, err, "dimensions must be at least 1, got 0")
+
+	_, err = qlearning.NewLinearAgent(1, qlearning.ConstantRate(1), 1.5, 0)
+	assert.EqualError(t, err, "discount factor must be between 0 and 1, got 1.5")
+
+	_, err = qlearning.NewLinearAgent(1, qlearning.ConstantRate(1), math.NaN(), 0)
+	assert.EqualError(t, err, "discount factor must be between 0 and 1, got NaN")
+
+	_, err = qlearning.NewLinearAgent(1, qlearning.ConstantRate(1), 0, -.1)
+	assert.EqualError(t, err, "regularization must not be negative, got -0.1")
+
+	a, err := qlearning.NewLinearAgent(1, qlearning.ConstantRate(1), 0, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	err = a.SetAgentContext(qlearning.LinearAgentContext{Dimensions: 1, LearningRate: qlearning.VisitDecay(1, .8, 0)})
+	assert.EqualError(t, err, "linear agent does not support the 'visit-decay' learning rate schedule")
+
+	err = a.SetAgentContext(qlearning.LinearAgentContext{Dimensions: 1, LearningRate: qlearning.ConstantRate(0)})
+	assert.EqualError(t, err, "initial learning rate must be positive and finite, got 0")
+
+	err = a.SetAgentContext(qlearning.LinearAgentContext{LearningRate: qlearning.ConstantRate(1)})
+	assert.EqualError(t, err, "dimensions must be at least 1, got 0")
+
+	err = a.SetAgentContext(qlearning.LinearAgentContext{Dimensions: 1, LearningRate: qlearning.ConstantRate(1), DiscountFactor: -1})
+	assert.EqualError(t, err, "discount factor must be between 0 and 1, got -1")
+
+	err = a.SetAgentContext(qlearning.LinearAgentContext{Dimensions: 1, LearningRate: qlearning.ConstantRate(1), Regularization: math.NaN()})
+	assert.EqualError(t, err, "regularization must not be negative, got NaN")
+	assert.Equal(t, 1, a.GetAgentContext().Dimensions)
+}
+
+func Test_LinearAgentSeed(t *testing.T) {
+	actions := []iface.Actioner{testAction("A"), testAction("B"), testAction("C"), testAction("D")}
+	state := &featureState{[]float64{1}, actions}
+	recommendations := func() []string {
+		a, err := qlearning.NewLinearAgent(1, qlearning.ConstantRate(1), 0, 0, qlearning.WithLinearSeed(7))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for i := 0; i < 20; i++ {
+			action, err := a.RecommendAction(state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, action.ID())
+		}
+		return ids
+	}
+	assert.Equal(t, recommendations(), recommendations())
+}
@@ -0,0 +1,264 @@
+package qlearning
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Default values used by NewBayesianAgentWithOptions when the corresponding
+// option is not supplied.
+const (
+	DefaultPrimingThreshold = 1
+	DefaultLearningRate     = 0.1
+	DefaultDiscountFactor   = 0.9
+)
+
+// Option configures a BayesianAgent. An Option returns an error if the value
+// it is configured with is invalid.
+type Option func(*BayesianAgent) error
+
+// NewBayesianAgentWithOptions returns a reference to a new BayesianAgent
+// configured by the supplied options, which are applied in order. Any setting
+// that is not configured by an option takes its default value (see
+// DefaultPrimingThreshold, DefaultLearningRate, and DefaultDiscountFactor).
+// An error is returned if any option is invalid.
+func NewBayesianAgentWithOptions(opts ...Option) (*BayesianAgent, error) {
+	a := NewBayesianAgent(DefaultPrimingThreshold, DefaultLearningRate, DefaultDiscountFactor)
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+	if a.hierarchical {
+		a.prior = HierarchicalPrior(a.hierarchyThreshold, a.prior)
+	}
+	if c, ok := a.store.(ClockSetter); ok {
+		c.SetClock(a.clock)
+	}
+	if n, ok := a.store.(EvictionNotifier); ok {
+		n.AddEvictListener(a.forgetState)
+	}
+	a.observeData(a.store.GetData(), false)
+	return a, nil
+}
+
+// WithPrimingThreshold sets the number of observations required of any action
+// before the action's raw q-value is trusted more than the average q-value
+// for all of a state's actions. n must not be negative.
+func WithPrimingThreshold(n int) Option {
+	return func(a *BayesianAgent) error {
+		if n < 0 {
+			return fmt.Errorf("priming threshold must not be negative, got %v", n)
+		}
+		a.primingThreshold = n
+		return nil
+	}
+}
+
+// WithLearningRate sets the extent to which newly acquired information
+// overrides old information. rate must be positive and finite.
+func WithLearningRate(rate float64) Option {
+	return func(a *BayesianAgent) error {
+		if !(rate > 0) || math.IsInf(rate, 1) {
+			return fmt.Errorf("learning rate must be positive and finite, got %v", rate)
+		}
+		a.learningRate = ConstantRate(rate)
+		return nil
+	}
+}
+
+// WithLearningRateSchedule sets the schedule that determines the learning rate
+// applied at each step. See ConstantRate, InverseTimeDecay, ExponentialDecay,
+// and VisitDecay. The schedule is persisted with the agent's context.
+func WithLearningRateSchedule(schedule Schedule) Option {
+	return func(a *BayesianAgent) error {
+		if err := schedule.validate(); err != nil {
+			return err
+		}
+		a.learningRate = schedule
+		return nil
+	}
+}
+
+// WithDiscount sets the importance of future rewards. factor must be between
+// 0 and 1 inclusive.
+func WithDiscount(factor float64) Option {
+	return func(a *BayesianAgent) error {
+		if !(factor >= 0 && factor <= 1) {
+			return fmt.Errorf("discount factor must be between 0 and 1, got %v", factor)
+		}
+		a.discountFactor = factor
+		return nil
+	}
+}
+
+// WithSeed seeds the agent's random source, making its random choices
+// reproducible. See BayesianAgent.SetSeed.
+func WithSeed(seed int64) Option {
+	return func(a *BayesianAgent) error {
+		a.SetSeed(seed)
+		return nil
+	}
+}
+
+// WithRand sets the agent's random source. See BayesianAgent.SetRandSource.
+func WithRand(source rand.Source) Option {
+	return func(a *BayesianAgent) error {
+		if source == nil {
+			return fmt.Errorf("random source must not be nil")
+		}
+		a.SetRandSource(source)
+		return nil
+	}
+}
+
+// WithTieBreaker sets the function used to choose between actions of equal
+// value. The function is supplied the number of tied actions, and must return
+// the index of the chosen action.
+func WithTieBreaker(tieBreaker func(int) int) Option {
+	return func(a *BayesianAgent) error {
+		if tieBreaker == nil {
+			return fmt.Errorf("tie breaker must not be nil")
+		}
+		a.TieBreaker = tieBreaker
+		return nil
+	}
+}
+
+// WithClock sets the function from which the agent reads the current time,
+// which defaults to time.Now. The clock times decisions, attribution windows,
+// and forgetting (see WithForgetting). If the agent's store is a ClockSetter,
+// as BoundedStore is, the store is given the same clock.
+func WithClock(clock func() time.Time) Option {
+	return func(a *BayesianAgent) error {
+		if clock == nil {
+			return fmt.Errorf("clock must not be nil")
+		}
+		a.clock = clock
+		return nil
+	}
+}
+
+// WithStore sets the store in which the agent records the stats of each
+// state's actions. By default, stats are stored in memory.
+func WithStore(store iface.QStorer) Option {
+	return func(a *BayesianAgent) error {
+		if store == nil {
+			return fmt.Errorf("store must not be nil")
+		}
+		a.store = store
+		return nil
+	}
+}
+
+// WithPrior sets the Prior toward which the agent weights the raw q-value of
+// actions that have been called few times. By default, SiblingMeanPrior is
+// used. The prior is not persisted with the agent's context. If the prior is a
+// StatsObserver, it is informed of the stats already in the agent's store, and
+// of every subsequent change the agent makes to them.
+func WithPrior(prior Prior) Option {
+	return func(a *BayesianAgent) error {
+		if prior == nil {
+			return fmt.Errorf("prior must not be nil")
+		}
+		a.prior = prior
+		return nil
+	}
+}
+
+// WithHierarchy enables hierarchical back-off for states that implement
+// iface.Parenter. Each transition the agent learns from is also pooled into
+// every ancestor of the transition's previous state, and the agent's prior is
+// wrapped by HierarchicalPrior, so that the prior for an action backs off to
+// the action's value in ancestor states. The agent's prior (SiblingMeanPrior
+// unless set by WithPrior, before or after this option) is used at the root of
+// the hierarchy. threshold must not be negative.
+func WithHierarchy(threshold float64) Option {
+	return func(a *BayesianAgent) error {
+		if !(threshold >= 0) {
+			return fmt.Errorf("hierarchy threshold must not be negative, got %v", threshold)
+		}
+		a.hierarchyThreshold = threshold
+		a.hierarchical = true
+		return nil
+	}
+}
+
+// WithWeighter sets the Weighter that combines each action's raw q-value with
+// its prior. By default, BayesianAverageWeighter is used. If the weighter is an
+// UpdateBaser, it also determines the q-value from which Learn updates each
+// action. The weighter is not persisted with the agent's context.
+func WithWeighter(weighter Weighter) Option {
+	return func(a *BayesianAgent) error {
+		if weighter == nil {
+			return fmt.Errorf("weighter must not be nil")
+		}
+		a.weighter = weighter
+		return nil
+	}
+}
+
+// WithExploration makes the agent explore with an epsilon-greedy policy, in
+// which RecommendAction recommends a random action (rather than the best known
+// action) with probability equal to the rate of the schedule at the current
+// step. The initial rate must be between 0 and 1 inclusive; a rate of 0
+// disables exploration. The schedule is persisted with the agent's context.
+func WithExploration(schedule Schedule) Option {
+	return func(a *BayesianAgent) error {
+		if err := schedule.ValidateExploration(); err != nil {
+			return err
+		}
+		a.exploration = &schedule
+		return nil
+	}
+}
+
+// WithConfidencePolicy makes the agent decline to recommend the best action
+// for a state when it lacks evidence that the action is best. In that case,
+// RecommendAction returns the policy's fallback action or, if there is none,
+// an error wrapping ErrLowConfidence. The policy does not apply to actions
+// chosen at random for the sake of exploration. The policy is not persisted
+// with the agent's context.
+func WithConfidencePolicy(policy ConfidencePolicy) Option {
+	return func(a *BayesianAgent) error {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		a.confidence = &policy
+		return nil
+	}
+}
+
+// Update describes a single update of an action's q-value by Learn.
+type Update struct {
+	StateID  string
+	ActionID string
+	// Step is the agent step at which the update occurred.
+	Step   int
+	Reward float64
+	// Target is the reward plus the discounted value of the best action of
+	// the next state.
+	Target float64
+	// TDError is the difference between the target and the q-value from which
+	// the action was updated (see UpdateBaser).
+	TDError float64
+	// QValueRaw is the action's raw q-value after the update.
+	QValueRaw float64
+}
+
+// WithUpdateHook sets a function that is called with every update the agent
+// makes to an action's q-value, such as for monitoring. The hook is called
+// synchronously from Learn, so should return quickly.
+func WithUpdateHook(hook func(Update)) Option {
+	return func(a *BayesianAgent) error {
+		if hook == nil {
+			return fmt.Errorf("update hook must not be nil")
+		}
+		a.updateHook = hook
+		return nil
+	}
+}
@@ -0,0 +1,196 @@
+package qlearning_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewBayesianAgentWithOptionsDefaults(t *testing.T) {
+	a, err := qlearning.NewBayesianAgentWithOptions()
+	if assert.NoError(t, err) {
+		c := a.GetAgentContext()
+		assert.Equal(t, qlearning.DefaultPrimingThreshold, c.PrimingThreshold)
+		assert.Equal(t, qlearning.DefaultLearningRate, c.LearningRate)
+		assert.Equal(t, qlearning.DefaultDiscountFactor, c.DiscountFactor)
+	}
+}
+
+func Test_NewBayesianAgentWithOptions(t *testing.T) {
+	randState, _ := qlearning.NewSource(3).MarshalBinary()
+
+	a, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(5),
+		qlearning.WithLearningRate(.25),
+		qlearning.WithDiscount(1),
+		qlearning.WithSeed(3),
+	)
+	if assert.NoError(t, err) {
+		c := a.GetAgentContext()
+		assert.Equal(t, 5, c.PrimingThreshold)
+		assert.Equal(t, .25, c.LearningRate)
+		assert.Equal(t, 1.0, c.DiscountFactor)
+		assert.Equal(t, randState, c.RandState)
+	}
+}
+
+func Test_NewBayesianAgentWithOptionsValidation(t *testing.T) {
+	testCases := []struct {
+		name   string
+		option qlearning.Option
+		exp    string
+	}{
+		{"negative priming threshold", qlearning.WithPrimingThreshold(-1), "priming threshold must not be negative, got -1"},
+		{"zero learning rate", qlearning.WithLearningRate(0), "learning rate must be positive and finite, got 0"},
+		{"NaN learning rate", qlearning.WithLearningRate(math.NaN()), "learning rate must be positive and finite, got NaN"},
+		{"infinite learning rate", qlearning.WithLearningRate(math.Inf(1)), "learning rate must be positive and finite, got +Inf"},
+		{"negative discount", qlearning.WithDiscount(-.1), "discount factor must be between 0 and 1, got -0.1"},
+		{"discount above one", qlearning.WithDiscount(1.1), "discount factor must be between 0 and 1, got 1.1"},
+		{"nil source", qlearning.WithRand(nil), "random source must not be nil"},
+		{"zero schedule", qlearning.WithLearningRateSchedule(qlearning.Schedule{}), "initial learning rate must be positive and finite, got 0"},
+		{"unknown schedule", qlearning.WithLearningRateSchedule(qlearning.Schedule{Kind: "x", Initial: 1}), "unknown schedule kind 'x'"},
+		{"floor above initial", qlearning.WithLearningRateSchedule(qlearning.ExponentialDecay(.5, .9, 1)), "learning rate floor must be between 0 and the initial rate, got 1"},
+		{"exponential growth", qlearning.WithLearningRateSchedule(qlearning.ExponentialDecay(1, 2, 0)), "exponential decay must be greater than 0 and at most 1, got 2"},
+		{"negative inverse time", qlearning.WithLearningRateSchedule(qlearning.InverseTimeDecay(1, -1, 0)), "inverse time decay must not be negative, got -1"},
+		{"visit decay omega", qlearning.WithLearningRateSchedule(qlearning.VisitDecay(1, 2, 0)), "visit decay omega must be greater than 0 and at most 1, got 2"},
+		{"nil tie breaker", qlearning.WithTieBreaker(nil), "tie breaker must not be nil"},
+		{"nil store", qlearning.WithStore(nil), "store must not be nil"},
+		{"nil clock", qlearning.WithClock(nil), "clock must not be nil"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := qlearning.NewBayesianAgentWithOptions(tc.option)
+			assert.Nil(t, a)
+			assert.EqualError(t, err, tc.exp)
+		})
+	}
+}
+
+func Test_WithStore(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	store := &recordingStore{}
+	a, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithStore(store),
+		qlearning.WithTieBreaker(func(int) int { return 0 }),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	action := agent.NewMockActioner(mc)
+	action.EXPECT().ID().Return("X").AnyTimes()
+	state := agent.NewMockStater(mc)
+	state.EXPECT().ID().Return("A").AnyTimes()
+	state.EXPECT().PossibleActions().Return([]iface.Actioner{action}).AnyTimes()
+	state.EXPECT().GetAction("X").Return(action, nil)
+
+	_, err = a.RecommendAction(state)
+	assert.NoError(t, err)
+	assert.Contains(t, store.data, "A")
+}
+
+// recordingStore is a minimal iface.QStorer.
+type recordingStore struct {
+	data map[string]map[string]iface.ActionStatter
+}
+
+func (s *recordingStore) GetStats(state iface.Stater, action iface.Actioner) (iface.ActionStatter, bool) {
+	stats, found := s.GetActionsForState(state)[action.ID()]
+	return stats, found
+}
+
+func (s *recordingStore) UpdateStats(state iface.Stater, action iface.Actioner, stats iface.ActionStatter) {
+	s.GetActionsForState(state)[action.ID()] = stats
+}
+
+func (s *recordingStore) GetActionsForState(state iface.Stater) map[string]iface.ActionStatter {
+	if s.data == nil {
+		s.data = map[string]map[string]iface.ActionStatter{}
+	}
+	if _, found := s.data[state.ID()]; !found {
+		s.data[state.ID()] = map[string]iface.ActionStatter{}
+	}
+	return s.data[state.ID()]
+}
+
+func (s *recordingStore) GetData() map[string]map[string]iface.ActionStatter {
+	return s.data
+}
+
+func (s *recordingStore) SetData(data map[string]map[string]iface.ActionStatter) {
+	s.data = data
+}
+
+func Test_WithLearningRateSchedule(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	action := agent.NewMockActioner(mc)
+	action.EXPECT().ID().Return("X").AnyTimes()
+	previous := agent.NewMockStater(mc)
+	previous.EXPECT().ID().Return("A").AnyTimes()
+	previous.EXPECT().PossibleActions().Return([]iface.Actioner{action}).AnyTimes()
+	current := agent.NewMockStater(mc)
+	current.EXPECT().ID().Return("B").AnyTimes()
+	current.EXPECT().PossibleActions().Return([]iface.Actioner{}).AnyTimes()
+
+	a, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithDiscount(0),
+		qlearning.WithLearningRateSchedule(qlearning.VisitDecay(1, 1, 0)),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// With a rate of 1/n, and no priming, the q-value is the running mean of
+	// the observed rewards.
+	for _, reward := range []float64{4, 2, 6, 0} {
+		a.Learn(previous, action, current, reward)
+	}
+	c := a.GetAgentContext()
+	assert.Equal(t, 3.0, c.QValues["A"]["X"].QValueRaw())
+	assert.Equal(t, 4, c.Steps)
+	if assert.NotNil(t, c.LearningRateSchedule) {
+		assert.Equal(t, qlearning.VisitDecay(1, 1, 0), *c.LearningRateSchedule)
+	}
+
+	restored := qlearning.NewBayesianAgent(0, .5, 0)
+	restored.SetAgentContext(c)
+	assert.Equal(t, c.LearningRateSchedule, restored.GetAgentContext().LearningRateSchedule)
+	assert.Equal(t, 4, restored.GetAgentContext().Steps)
+}
+
+func Test_WithUpdateHook(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	updates := []qlearning.Update{}
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithLearningRate(.5),
+		qlearning.WithDiscount(0),
+		qlearning.WithUpdateHook(func(u qlearning.Update) { updates = append(updates, u) }),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, x, terminal, 4)
+	ba.Learn(a, x, terminal, 4)
+
+	assert.Equal(t, []qlearning.Update{
+		{StateID: "A", ActionID: "X", Step: 0, Reward: 4, Target: 4, TDError: 4, QValueRaw: 2},
+		{StateID: "A", ActionID: "X", Step: 1, Reward: 4, Target: 4, TDError: 2, QValueRaw: 3},
+	}, updates)
+
+	_, err = qlearning.NewBayesianAgentWithOptions(qlearning.WithUpdateHook(nil))
+	assert.Error(t, err)
+}
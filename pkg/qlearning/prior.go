@@ -0,0 +1,130 @@
+package qlearning
+
+import (
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	qlmath "github.com/eltorocorp/reinforcement-learning/pkg/qlearning/internal/math"
+)
+
+// A Prior provides the estimated q-value toward which a BayesianAgent weights
+// an action's raw q-value. The fewer times an action has been called, the
+// closer its weighted q-value is to its prior.
+type Prior interface {
+	// Estimate returns the prior q-value for the specified action of a state.
+	// The store contains every stat the agent has recorded thus far, and
+	// must not be modified.
+	Estimate(store iface.QStorer, state iface.Stater, actionID string) float64
+}
+
+// PriorFunc adapts an ordinary function to the Prior interface.
+type PriorFunc func(store iface.QStorer, state iface.Stater, actionID string) float64
+
+// Estimate calls f(store, state, actionID).
+func (f PriorFunc) Estimate(store iface.QStorer, state iface.Stater, actionID string) float64 {
+	return f(store, state, actionID)
+}
+
+// SiblingMeanPrior returns a Prior that estimates every action of a state to
+// be worth the mean raw q-value of the state's possible actions that have
+// been recorded thus far. This is the BayesianAgent's default prior.
+func SiblingMeanPrior() Prior {
+	return PriorFunc(siblingMean)
+}
+
+func siblingMean(store iface.QStorer, state iface.Stater, _ string) float64 {
+	rawValueSum := 0.0
+	existingActionCount := 0.0
+	for _, action := range state.PossibleActions() {
+		if stats, found := store.GetStats(state, action); found {
+			rawValueSum += nanToZero(stats.QValueRaw())
+			existingActionCount++
+		}
+	}
+	return qlmath.SafeDivide(rawValueSum, existingActionCount)
+}
+
+// FixedPrior returns a Prior that estimates every action of every state to be
+// worth value. This is useful when a global average reward is known in
+// advance.
+func FixedPrior(value float64) Prior {
+	return PriorFunc(func(iface.QStorer, iface.Stater, string) float64 {
+		return value
+	})
+}
+
+// OptimisticPrior returns a Prior that estimates every action of every state
+// to be worth value, which should exceed any q-value the agent could
+// realistically observe. Until an action has been called enough times to
+// overcome the priming threshold, it appears more valuable than actions that
+// have been tried, thus encouraging the agent to try every action.
+func OptimisticPrior(value float64) Prior {
+	return FixedPrior(value)
+}
+
+// StatsObserver is an optional interface that a Prior may implement to be
+// informed of every change the agent makes to the stats of an action, so
+// that the prior can maintain running totals rather than scanning the store
+// for every estimate. ObserveStats is called after an action's stats are
+// updated, and with nil stats after an action's stats are removed from the
+// agent's store.
+type StatsObserver interface {
+	ObserveStats(stateID, actionID string, stats iface.ActionStatter)
+}
+
+// ActionAveragePrior returns a Prior that estimates an action to be worth the
+// mean raw q-value of the same action (by ID) across every state in which it
+// has been called. This allows what has been learned about an action in one
+// state to inform its value in states where it is new. If the action has not
+// been called in any state, the prior falls back to SiblingMeanPrior.
+//
+// The prior keeps a running total of each action's raw q-values, which it
+// maintains as a StatsObserver, so it must not be shared between agents.
+func ActionAveragePrior() Prior {
+	return &actionAveragePrior{
+		values: make(map[string]map[string]float64),
+		sums:   make(map[string]float64),
+		counts: make(map[string]int),
+	}
+}
+
+type actionAveragePrior struct {
+	// values holds the raw q-value of every called action, by state ID and
+	// action ID, as included in sums and counts.
+	values map[string]map[string]float64
+	sums   map[string]float64
+	counts map[string]int
+}
+
+func (p *actionAveragePrior) Estimate(store iface.QStorer, state iface.Stater, actionID string) float64 {
+	count := p.counts[actionID]
+	if count == 0 {
+		return siblingMean(store, state, actionID)
+	}
+	return p.sums[actionID] / float64(count)
+}
+
+func (p *actionAveragePrior) ObserveStats(stateID, actionID string, stats iface.ActionStatter) {
+	if value, found := p.values[stateID][actionID]; found {
+		delete(p.values[stateID], actionID)
+		if len(p.values[stateID]) == 0 {
+			delete(p.values, stateID)
+		}
+		p.counts[actionID]--
+		p.sums[actionID] -= value
+		if p.counts[actionID] == 0 {
+			delete(p.counts, actionID)
+			delete(p.sums, actionID)
+		}
+	}
+	if stats == nil || stats.Calls() == 0 {
+		return
+	}
+	value := nanToZero(stats.QValueRaw())
+	if p.values[stateID] == nil {
+		p.values[stateID] = make(map[string]float64)
+	}
+	p.values[stateID][actionID] = value
+	p.counts[actionID]++
+	p.sums[actionID] += value
+}
+
+var _ StatsObserver = (*actionAveragePrior)(nil)
@@ -0,0 +1,188 @@
+package qlearning_test
+
+import (
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPriorFixture returns two states, A and B, which share actions X and Y,
+// and a terminal state T with no actions.
+func newPriorFixture(mc *gomock.Controller) (x, y iface.Actioner, a, b, terminal iface.Stater) {
+	actionX := agent.NewMockActioner(mc)
+	actionX.EXPECT().ID().Return("X").AnyTimes()
+	actionY := agent.NewMockActioner(mc)
+	actionY.EXPECT().ID().Return("Y").AnyTimes()
+
+	stateA := agent.NewMockStater(mc)
+	stateA.EXPECT().ID().Return("A").AnyTimes()
+	stateA.EXPECT().PossibleActions().Return([]iface.Actioner{actionX, actionY}).AnyTimes()
+
+	stateB := agent.NewMockStater(mc)
+	stateB.EXPECT().ID().Return("B").AnyTimes()
+	stateB.EXPECT().PossibleActions().Return([]iface.Actioner{actionX, actionY}).AnyTimes()
+
+	stateT := agent.NewMockStater(mc)
+	stateT.EXPECT().ID().Return("T").AnyTimes()
+	stateT.EXPECT().PossibleActions().Return([]iface.Actioner{}).AnyTimes()
+
+	return actionX, actionY, stateA, stateB, stateT
+}
+
+func weightedValues(a *qlearning.BayesianAgent, stateID string) map[string]float64 {
+	values := map[string]float64{}
+	for id, stats := range a.GetAgentContext().QValues[stateID] {
+		values[id] = stats.QValueWeighted()
+	}
+	return values
+}
+
+func Test_FixedPrior(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(1),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithPrior(qlearning.FixedPrior(10)),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, x, terminal, 2)
+
+	// X: (1*10 + 1*2) / 2, Y: never called, so entirely the prior.
+	assert.Equal(t, map[string]float64{"X": 6, "Y": 10}, weightedValues(ba, "A"))
+}
+
+func Test_OptimisticPrior(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(3),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithPrior(qlearning.OptimisticPrior(100)),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, x, terminal, 4)
+
+	// X: (3*100 + 1*4) / 4, so Y, which has never been tried, remains the
+	// more valuable action.
+	assert.Equal(t, map[string]float64{"X": 76, "Y": 100}, weightedValues(ba, "A"))
+}
+
+func Test_ActionAveragePrior(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, y, a, b, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(1),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithPrior(qlearning.ActionAveragePrior()),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, x, terminal, 4)
+	ba.Learn(a, y, terminal, 1)
+	ba.Learn(b, y, terminal, 3)
+
+	// In state B, X has never been called, so its prior is its value in A.
+	// Y's prior is the mean of its raw value in A and B.
+	assert.Equal(t, map[string]float64{"X": 4, "Y": 2.5}, weightedValues(ba, "B"))
+}
+
+func Test_ActionAveragePriorFallsBackToSiblingMean(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(1),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithPrior(qlearning.ActionAveragePrior()),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, x, terminal, 4)
+
+	// Y has never been called anywhere, so its prior is the mean of its
+	// recorded siblings, of which there is only X.
+	assert.Equal(t, 4.0, weightedValues(ba, "A")["Y"])
+}
+
+func Test_ActionAveragePriorRestored(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, y, a, b, terminal := newPriorFixture(mc)
+
+	newAgent := func() *qlearning.BayesianAgent {
+		ba, err := qlearning.NewBayesianAgentWithOptions(
+			qlearning.WithPrimingThreshold(1),
+			qlearning.WithLearningRate(1),
+			qlearning.WithDiscount(0),
+			qlearning.WithPrior(qlearning.ActionAveragePrior()),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ba
+	}
+	original := newAgent()
+	original.Learn(a, x, terminal, 4)
+
+	// The restored agent's prior accounts for the stats it was restored
+	// with, so X's prior in B is its value in A.
+	restored := newAgent()
+	restored.SetAgentContext(original.GetAgentContext())
+	restored.Learn(b, y, terminal, 0)
+	assert.Equal(t, 4.0, weightedValues(restored, "B")["X"])
+}
+
+func Test_PriorFunc(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	calls := map[string]int{}
+	prior := qlearning.PriorFunc(func(store iface.QStorer, state iface.Stater, actionID string) float64 {
+		calls[state.ID()+actionID]++
+		if actionID == "Y" {
+			return -1
+		}
+		return 1
+	})
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(1),
+		qlearning.WithPrior(prior),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, x, terminal, 0)
+
+	// X's prior is estimated once for the q-value from which it is updated,
+	// and again when A's actions are weighted after the update.
+	assert.Equal(t, map[string]int{"AX": 2, "AY": 1}, calls)
+	assert.Equal(t, -1.0, weightedValues(ba, "A")["Y"])
+}
+
+func Test_WithPriorValidation(t *testing.T) {
+	_, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithPrior(nil))
+	assert.EqualError(t, err, "prior must not be nil")
+}
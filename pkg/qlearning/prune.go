@@ -0,0 +1,53 @@
+package qlearning
+
+import (
+	"sort"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// ArchiveFunc receives the stats of an action that is being removed from the
+// agent, so that they can be retained elsewhere.
+type ArchiveFunc func(stateID, actionID string, stats iface.ActionStatter)
+
+// PruneActions removes the stats of every action recorded for a state that
+// is not among the state's current possible actions, such as an action that
+// has been retired. If archive is not nil, it is called with the stats of each
+// action before the action is removed. The IDs of the removed actions are
+// returned in a consistent order.
+//
+// Pruning is never required for correctness, since the agent only recommends
+// possible actions. It reclaims the memory used by retired actions, and stops
+// them from influencing priors and weighting.
+func (a *BayesianAgent) PruneActions(state iface.Stater, archive ArchiveFunc) []string {
+	possible := make(map[string]bool)
+	for _, action := range state.PossibleActions() {
+		possible[action.ID()] = true
+	}
+
+	actions := a.store.GetActionsForState(state)
+	stale := []string{}
+	for actionID := range actions {
+		if !possible[actionID] {
+			stale = append(stale, actionID)
+		}
+	}
+	sort.Strings(stale)
+
+	deleter, canDelete := a.store.(iface.StatsDeleter)
+	for _, actionID := range stale {
+		if archive != nil {
+			archive(state.ID(), actionID, actions[actionID])
+		}
+		if canDelete {
+			deleter.DeleteStats(state, actionID)
+		} else {
+			delete(actions, actionID)
+		}
+		a.forget(state.ID(), actionID)
+	}
+	if len(stale) > 0 {
+		a.applyActionWeights(state)
+	}
+	return stale
+}
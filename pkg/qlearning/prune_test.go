@@ -0,0 +1,103 @@
+package qlearning_test
+
+import (
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDynamicState returns a state A whose possible actions are whichever of X
+// and Y are currently listed in offered.
+func newDynamicState(mc *gomock.Controller, offered *[]string) (actions map[string]iface.Actioner, state iface.Stater) {
+	actions = map[string]iface.Actioner{}
+	for _, id := range []string{"X", "Y"} {
+		action := agent.NewMockActioner(mc)
+		action.EXPECT().ID().Return(id).AnyTimes()
+		actions[id] = action
+	}
+	stateA := agent.NewMockStater(mc)
+	stateA.EXPECT().ID().Return("A").AnyTimes()
+	stateA.EXPECT().PossibleActions().DoAndReturn(func() []iface.Actioner {
+		possible := []iface.Actioner{}
+		for _, id := range *offered {
+			possible = append(possible, actions[id])
+		}
+		return possible
+	}).AnyTimes()
+	stateA.EXPECT().GetAction(gomock.Any()).DoAndReturn(func(id string) (iface.Actioner, error) {
+		return actions[id], nil
+	}).AnyTimes()
+	return actions, stateA
+}
+
+func Test_RecommendActionIgnoresRetiredActions(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	offered := []string{"X", "Y"}
+	actions, state := newDynamicState(mc, &offered)
+	_, _, _, _, terminal := newPriorFixture(mc)
+
+	ba := qlearning.NewBayesianAgent(0, 1, 0)
+	ba.Learn(state, actions["X"], terminal, 10)
+	ba.Learn(state, actions["Y"], terminal, 1)
+
+	offered = []string{"Y"}
+	action, err := ba.RecommendAction(state)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Y", action.ID())
+	}
+
+	offered = []string{}
+	_, err = ba.RecommendAction(state)
+	assert.Error(t, err)
+}
+
+func Test_RecommendActionWithMask(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	actions, state, terminal := newRecommendFixture(mc, "X", "Y")
+
+	ba := qlearning.NewBayesianAgent(0, 1, 0)
+	ba.Learn(state, actions["X"], terminal, 10)
+
+	notX := func(action iface.Actioner) bool { return action.ID() != "X" }
+	action, err := ba.RecommendActionWithMask(state, notX)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Y", action.ID())
+	}
+
+	none := func(iface.Actioner) bool { return false }
+	_, err = ba.RecommendActionWithMask(state, none)
+	assert.Error(t, err)
+}
+
+func Test_PruneActions(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	offered := []string{"X", "Y"}
+	actions, state := newDynamicState(mc, &offered)
+	_, _, _, _, terminal := newPriorFixture(mc)
+
+	ba := qlearning.NewBayesianAgent(1, 1, 0)
+	ba.Learn(state, actions["X"], terminal, 10)
+	ba.Learn(state, actions["Y"], terminal, 2)
+
+	offered = []string{"Y"}
+	archived := map[string]float64{}
+	pruned := ba.PruneActions(state, func(stateID, actionID string, stats iface.ActionStatter) {
+		assert.Equal(t, "A", stateID)
+		archived[actionID] = stats.QValueRaw()
+	})
+
+	assert.Equal(t, []string{"X"}, pruned)
+	assert.Equal(t, map[string]float64{"X": 10}, archived)
+	qValues := ba.GetAgentContext().QValues["A"]
+	assert.NotContains(t, qValues, "X")
+	assert.Equal(t, 2.0, qValues["Y"].QValueWeighted())
+
+	assert.Empty(t, ba.PruneActions(state, nil))
+}
@@ -0,0 +1,55 @@
+package qlearning
+
+import (
+	"sort"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// RankedAction describes one of a state's actions, as ranked by RankActions.
+type RankedAction struct {
+	ActionID       string
+	QValueRaw      float64
+	QValueWeighted float64
+	Calls          int
+	// Probability is the probability that RecommendAction would currently
+	// recommend the action, assuming the agent's tie breaker chooses
+	// uniformly between tied actions (as the default tie breaker does). It
+	// accounts for exploration and the agent's ConfidencePolicy, and is the
+	// propensity with which the action's Decision would be logged.
+	Probability float64
+}
+
+// RankActions returns every possible action of a state, ordered from greatest
+// to least weighted q-value. Actions of equal value are ordered by ID. Ranking
+// actions does not consume any randomness, nor does it modify the agent's
+// store, so it does not affect what the agent learns. If the agent has a
+// ConfidencePolicy with a fallback, a fallback action that is not one of the
+// state's possible actions is not ranked. An error is returned if the state
+// has no actions, or if the fallback returns an error.
+func (a *BayesianAgent) RankActions(state iface.Stater) ([]RankedAction, error) {
+	d, err := a.consider(state, nil)
+	if err != nil {
+		return nil, err
+	}
+	probabilities, err := a.probabilities(state, d)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]RankedAction, len(d.actionIDs))
+	for i, actionID := range d.actionIDs {
+		stats := d.actions[actionID]
+		ranked[i] = RankedAction{
+			ActionID:       actionID,
+			QValueRaw:      stats.QValueRaw(),
+			QValueWeighted: d.weighted[actionID],
+			Calls:          stats.Calls(),
+			Probability:    probabilities[actionID],
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].QValueWeighted > ranked[j].QValueWeighted
+	})
+	return ranked, nil
+}
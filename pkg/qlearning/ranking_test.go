@@ -0,0 +1,194 @@
+package qlearning_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/mocks/agent"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RankActions(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, y, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ranked, err := ba.RankActions(a)
+	if assert.NoError(t, err) && assert.Len(t, ranked, 2) {
+		assert.Equal(t, "X", ranked[0].ActionID)
+		assert.Equal(t, .5, ranked[0].Probability)
+		assert.Equal(t, .5, ranked[1].Probability)
+	}
+
+	ba.Learn(a, x, terminal, 1)
+	ba.Learn(a, y, terminal, 3)
+	ranked, err = ba.RankActions(a)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []qlearning.RankedAction{
+			{ActionID: "Y", QValueRaw: 3, QValueWeighted: 3, Calls: 1, Probability: 1},
+			{ActionID: "X", QValueRaw: 1, QValueWeighted: 1, Calls: 1, Probability: 0},
+		}, ranked)
+	}
+
+	_, err = ba.RankActions(terminal)
+	assert.Error(t, err)
+}
+
+func Test_RankActionsLeavesAgentUnchanged(t *testing.T) {
+	actions := []iface.Actioner{testAction("X"), testAction("Y")}
+	a := &treeState{id: "A", actions: actions}
+	b := &treeState{id: "B", actions: actions}
+	terminal := &treeState{id: "terminal"}
+
+	now := time.Unix(0, 0)
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithSeed(1),
+		qlearning.WithExploration(qlearning.ConstantRate(.2)),
+		qlearning.WithClock(func() time.Time { return now }),
+		qlearning.WithForgetting(qlearning.Forgetting{HalfLife: time.Hour}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, testAction("X"), terminal, 1)
+
+	// The context shares the agent's maps, so is compared as JSON.
+	snapshot := func() string {
+		data, err := json.Marshal(ba.GetAgentContext())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	before := snapshot()
+
+	// The weights of A have decayed, and B has never been observed, but
+	// neither is recorded by ranking.
+	now = now.Add(time.Hour)
+	for _, state := range []iface.Stater{a, b} {
+		_, err := ba.RankActions(state)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, before, snapshot())
+}
+
+func Test_RankActionsWithExploration(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, y, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(0),
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithExploration(qlearning.ConstantRate(.2)),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, x, terminal, 1)
+	ba.Learn(a, y, terminal, 3)
+
+	ranked, err := ba.RankActions(a)
+	if assert.NoError(t, err) && assert.Len(t, ranked, 2) {
+		assert.InDelta(t, .9, ranked[0].Probability, 1e-12)
+		assert.InDelta(t, .1, ranked[1].Probability, 1e-12)
+	}
+}
+
+func Test_RecommendActionExplores(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+
+	actions := map[string]iface.Actioner{}
+	possible := []iface.Actioner{}
+	for _, id := range []string{"X", "Y"} {
+		action := agent.NewMockActioner(mc)
+		action.EXPECT().ID().Return(id).AnyTimes()
+		actions[id] = action
+		possible = append(possible, action)
+	}
+	state := agent.NewMockStater(mc)
+	state.EXPECT().ID().Return("A").AnyTimes()
+	state.EXPECT().PossibleActions().Return(possible).AnyTimes()
+	state.EXPECT().GetAction(gomock.Any()).DoAndReturn(func(id string) (iface.Actioner, error) {
+		return actions[id], nil
+	}).AnyTimes()
+	terminal := agent.NewMockStater(mc)
+	terminal.EXPECT().ID().Return("T").AnyTimes()
+	terminal.EXPECT().PossibleActions().Return([]iface.Actioner{}).AnyTimes()
+
+	newAgent := func(rate float64) *qlearning.BayesianAgent {
+		opts := []qlearning.Option{
+			qlearning.WithPrimingThreshold(0),
+			qlearning.WithLearningRate(1),
+			qlearning.WithDiscount(0),
+			qlearning.WithSeed(7),
+		}
+		if rate > 0 {
+			opts = append(opts, qlearning.WithExploration(qlearning.ConstantRate(rate)))
+		}
+		ba, err := qlearning.NewBayesianAgentWithOptions(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ba.Learn(state, actions["Y"], terminal, 1)
+		return ba
+	}
+
+	count := func(ba *qlearning.BayesianAgent) int {
+		explored := 0
+		for i := 0; i < 1000; i++ {
+			action, err := ba.RecommendAction(state)
+			if !assert.NoError(t, err) {
+				return 0
+			}
+			if action.ID() == "X" {
+				explored++
+			}
+		}
+		return explored
+	}
+
+	assert.Equal(t, 0, count(newAgent(0)))
+	// Exploration chooses uniformly between all actions, including the best.
+	explored := count(newAgent(.2))
+	assert.True(t, explored > 50 && explored < 150, "explored %v times", explored)
+
+	c := newAgent(.2).GetAgentContext()
+	if assert.NotNil(t, c.Exploration) {
+		assert.Equal(t, qlearning.ConstantRate(.2), *c.Exploration)
+	}
+	restored := qlearning.NewBayesianAgent(0, 1, 0)
+	restored.SetAgentContext(c)
+	assert.Equal(t, .2, restored.ExplorationRate())
+}
+
+func Test_WithExplorationInvalid(t *testing.T) {
+	_, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithExploration(qlearning.ConstantRate(1.5)))
+	assert.EqualError(t, err, "initial exploration rate must be between 0 and 1, got 1.5")
+	_, err = qlearning.NewBayesianAgentWithOptions(qlearning.WithExploration(qlearning.InverseTimeDecay(.5, .1, .6)))
+	assert.EqualError(t, err, "exploration rate floor must be between 0 and the initial rate, got 0.6")
+	_, err = qlearning.NewBayesianAgentWithOptions(qlearning.WithExploration(qlearning.ExponentialDecay(.5, 2, 0)))
+	assert.EqualError(t, err, "exponential decay must be greater than 0 and at most 1, got 2")
+}
+
+func Test_WithExplorationZero(t *testing.T) {
+	ba, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithExploration(qlearning.ConstantRate(0)))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0.0, ba.ExplorationRate())
+	}
+}
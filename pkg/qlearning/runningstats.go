@@ -0,0 +1,59 @@
+package qlearning
+
+import "math"
+
+// RunningStats accumulates the count, mean, and variance of a series of
+// values in a single pass, without retaining the values themselves.
+// see https://en.wikipedia.org/wiki/Algorithms_for_calculating_variance#Welford's_online_algorithm
+type RunningStats struct {
+	Count int
+	Mean  float64
+	// M2 is the sum of squared differences from the mean.
+	M2 float64
+}
+
+// Interval is a closed range of values.
+type Interval struct {
+	Low  float64
+	High float64
+}
+
+// Add includes value in the stats.
+func (rs *RunningStats) Add(value float64) {
+	rs.Count++
+	delta := value - rs.Mean
+	rs.Mean += delta / float64(rs.Count)
+	rs.M2 += delta * (value - rs.Mean)
+}
+
+// Variance returns the sample variance of the values added thus far, or 0 if
+// fewer than two values have been added.
+func (rs RunningStats) Variance() float64 {
+	if rs.Count < 2 {
+		return 0
+	}
+	return rs.M2 / float64(rs.Count-1)
+}
+
+// StdDev returns the sample standard deviation of the values added thus far.
+func (rs RunningStats) StdDev() float64 {
+	return math.Sqrt(rs.Variance())
+}
+
+// StdErr returns the standard error of the mean, or 0 if no values have been
+// added.
+func (rs RunningStats) StdErr() float64 {
+	if rs.Count == 0 {
+		return 0
+	}
+	return math.Sqrt(rs.Variance() / float64(rs.Count))
+}
+
+// ConfidenceInterval returns the interval within which the true mean lies
+// with the confidence implied by z, the number of standard errors either side
+// of the mean (1.96 for 95%). The interval is based on the normal
+// approximation, so is only meaningful once several values have been added.
+func (rs RunningStats) ConfidenceInterval(z float64) Interval {
+	margin := z * rs.StdErr()
+	return Interval{Low: rs.Mean - margin, High: rs.Mean + margin}
+}
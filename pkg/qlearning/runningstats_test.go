@@ -0,0 +1,57 @@
+package qlearning_test
+
+import (
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RunningStats(t *testing.T) {
+	var rs qlearning.RunningStats
+	assert.Equal(t, 0.0, rs.Variance())
+	assert.Equal(t, 0.0, rs.StdErr())
+
+	for _, v := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		rs.Add(v)
+	}
+	assert.Equal(t, 8, rs.Count)
+	assert.InDelta(t, 5, rs.Mean, 1e-12)
+	assert.InDelta(t, 32.0/7, rs.Variance(), 1e-12)
+	assert.InDelta(t, 0.7559289, rs.StdErr(), 1e-6)
+
+	ci := rs.ConfidenceInterval(2)
+	assert.InDelta(t, 5-2*rs.StdErr(), ci.Low, 1e-12)
+	assert.InDelta(t, 5+2*rs.StdErr(), ci.High, 1e-12)
+}
+
+func Test_BayesianAgentConfidenceInterval(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, y, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithDiscount(0))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = ba.ConfidenceInterval(a, x, 1.96)
+	assert.Error(t, err, "not recorded")
+
+	ba.Learn(a, x, terminal, 1)
+	_, err = ba.ConfidenceInterval(a, x, 1.96)
+	assert.Error(t, err, "too few observations")
+
+	ba.Learn(a, x, terminal, 3)
+	ba.Learn(a, y, terminal, 1)
+	ci, err := ba.ConfidenceInterval(a, x, 1.96)
+	if assert.NoError(t, err) {
+		assert.InDelta(t, 2-1.96, ci.Low, 1e-12)
+		assert.InDelta(t, 2+1.96, ci.High, 1e-12)
+	}
+
+	stats := ba.GetAgentContext().QValues["A"]["X"].(*qlearning.ActionStats)
+	assert.Equal(t, qlearning.RunningStats{Count: 2, Mean: 2, M2: 2}, stats.Rewards)
+	assert.Equal(t, 2.0, stats.Variance())
+}
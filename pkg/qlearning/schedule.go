@@ -0,0 +1,146 @@
+package qlearning
+
+import (
+	"fmt"
+	"math"
+)
+
+// ScheduleKind identifies the function used by a Schedule to compute a
+// learning rate.
+type ScheduleKind string
+
+const (
+	// ConstantSchedule always returns the schedule's initial rate.
+	ConstantSchedule ScheduleKind = "constant"
+
+	// InverseTimeSchedule returns initial / (1 + decay * step).
+	InverseTimeSchedule ScheduleKind = "inverse-time"
+
+	// ExponentialSchedule returns initial * decay^step.
+	ExponentialSchedule ScheduleKind = "exponential"
+
+	// VisitDecaySchedule returns initial / n^omega, where n is the number of
+	// times the state/action pair being updated has been visited. Each pair
+	// thus has its own learning rate.
+	VisitDecaySchedule ScheduleKind = "visit-decay"
+)
+
+// Schedule describes how a learning rate evolves as an agent gains
+// experience. Schedule is a plain value so that it can be persisted alongside
+// the rest of an agent's context.
+type Schedule struct {
+	Kind    ScheduleKind
+	Initial float64
+	Decay   float64
+	// Omega is the exponent applied to the visit count by a
+	// VisitDecaySchedule.
+	Omega float64 `json:",omitempty"`
+	// Floor is the minimum rate the schedule will return.
+	Floor float64
+}
+
+// ConstantRate returns a Schedule that always returns rate.
+func ConstantRate(rate float64) Schedule {
+	return Schedule{Kind: ConstantSchedule, Initial: rate}
+}
+
+// InverseTimeDecay returns a Schedule that decays from initial according to
+// initial / (1 + decay * step), but never below floor.
+func InverseTimeDecay(initial, decay, floor float64) Schedule {
+	return Schedule{Kind: InverseTimeSchedule, Initial: initial, Decay: decay, Floor: floor}
+}
+
+// ExponentialDecay returns a Schedule that decays from initial according to
+// initial * decay^step, but never below floor. decay is typically slightly
+// less than 1.
+func ExponentialDecay(initial, decay, floor float64) Schedule {
+	return Schedule{Kind: ExponentialSchedule, Initial: initial, Decay: decay, Floor: floor}
+}
+
+// VisitDecay returns a Schedule that decays the learning rate of each
+// state/action pair according to initial / n^omega, where n is the number of
+// times the pair has been visited, but never below floor.
+//
+// With an omega in (0.5, 1] and a floor of zero, the q-value estimates
+// converge even when rewards are stochastic.
+// see https://www.jmlr.org/papers/volume5/evendar03a/evendar03a.pdf
+func VisitDecay(initial, omega, floor float64) Schedule {
+	return Schedule{Kind: VisitDecaySchedule, Initial: initial, Omega: omega, Floor: floor}
+}
+
+// Rate returns the learning rate after the specified number of steps.
+// A VisitDecaySchedule returns its initial rate (subject to its floor), since
+// no visit count is supplied. A Schedule with an unrecognized Kind is treated
+// as constant.
+func (s Schedule) Rate(step int) float64 {
+	return s.RateAt(step, 1)
+}
+
+// RateAt returns the learning rate after the specified number of steps, for
+// a state/action pair that has been visited the specified number of times
+// (including the visit being learned from).
+func (s Schedule) RateAt(step, visits int) float64 {
+	var rate float64
+	switch s.Kind {
+	case VisitDecaySchedule:
+		if visits < 1 {
+			visits = 1
+		}
+		rate = s.Initial / math.Pow(float64(visits), s.Omega)
+	case InverseTimeSchedule:
+		rate = s.Initial / (1 + s.Decay*float64(step))
+	case ExponentialSchedule:
+		rate = s.Initial * math.Pow(s.Decay, float64(step))
+	default:
+		rate = s.Initial
+	}
+	return math.Max(rate, s.Floor)
+}
+
+// validate returns an error if the schedule can not produce sensible learning
+// rates.
+func (s Schedule) validate() error {
+	if !(s.Initial > 0) || math.IsInf(s.Initial, 1) {
+		return fmt.Errorf("initial learning rate must be positive and finite, got %v", s.Initial)
+	}
+	if !(s.Floor >= 0 && s.Floor <= s.Initial) {
+		return fmt.Errorf("learning rate floor must be between 0 and the initial rate, got %v", s.Floor)
+	}
+	return s.validateKind()
+}
+
+// ValidateExploration returns an error if the schedule can not produce
+// sensible exploration rates. Unlike a learning rate, an exploration rate may
+// be zero, which disables exploration.
+func (s Schedule) ValidateExploration() error {
+	if !(s.Initial >= 0 && s.Initial <= 1) {
+		return fmt.Errorf("initial exploration rate must be between 0 and 1, got %v", s.Initial)
+	}
+	if !(s.Floor >= 0 && s.Floor <= s.Initial) {
+		return fmt.Errorf("exploration rate floor must be between 0 and the initial rate, got %v", s.Floor)
+	}
+	return s.validateKind()
+}
+
+// validateKind returns an error if the schedule's kind is unknown, or its
+// decay parameters are invalid for its kind.
+func (s Schedule) validateKind() error {
+	switch s.Kind {
+	case ConstantSchedule:
+	case InverseTimeSchedule:
+		if !(s.Decay >= 0) {
+			return fmt.Errorf("inverse time decay must not be negative, got %v", s.Decay)
+		}
+	case ExponentialSchedule:
+		if !(s.Decay > 0 && s.Decay <= 1) {
+			return fmt.Errorf("exponential decay must be greater than 0 and at most 1, got %v", s.Decay)
+		}
+	case VisitDecaySchedule:
+		if !(s.Omega > 0 && s.Omega <= 1) {
+			return fmt.Errorf("visit decay omega must be greater than 0 and at most 1, got %v", s.Omega)
+		}
+	default:
+		return fmt.Errorf("unknown schedule kind '%v'", s.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package qlearning_test
+
+import (
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScheduleRate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schedule qlearning.Schedule
+		step     int
+		exp      float64
+	}{
+		{"constant", qlearning.ConstantRate(.5), 100, .5},
+		{"inverse time", qlearning.InverseTimeDecay(1, .5, 0), 2, .5},
+		{"inverse time floor", qlearning.InverseTimeDecay(1, .5, .6), 2, .6},
+		{"exponential", qlearning.ExponentialDecay(1, .5, 0), 3, .125},
+		{"exponential floor", qlearning.ExponentialDecay(1, .5, .2), 3, .2},
+		{"zero value", qlearning.Schedule{}, 10, 0},
+		{"visit decay ignores steps", qlearning.VisitDecay(1, 1, 0), 10, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, tc.schedule.Rate(tc.step))
+		})
+	}
+}
+
+func Test_ScheduleRateAt(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schedule qlearning.Schedule
+		visits   int
+		exp      float64
+	}{
+		{"first visit", qlearning.VisitDecay(1, 1, 0), 1, 1},
+		{"unvisited", qlearning.VisitDecay(1, 1, 0), 0, 1},
+		{"one over n", qlearning.VisitDecay(1, 1, 0), 4, .25},
+		{"one over root n", qlearning.VisitDecay(1, .5, 0), 4, .5},
+		{"floor", qlearning.VisitDecay(1, 1, .3), 4, .3},
+		{"step based ignores visits", qlearning.ConstantRate(.5), 4, .5},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, tc.schedule.RateAt(0, tc.visits))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package qlearning
+
+import (
+	"fmt"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+)
+
+// Session drives any iface.Agenter through episodes, remembering the previous
+// state and action so that callers need only supply each new state and the
+// reward that led to it. A Session is not safe for concurrent use.
+//
+// A typical loop is:
+//
+//	action, err := session.Step(state, 0)
+//	for action != nil && err == nil {
+//	    state, reward = applyToEnvironment(action)
+//	    action, err = session.Step(state, reward)
+//	}
+type Session struct {
+	agent          iface.Agenter
+	previousState  iface.Stater
+	previousAction iface.Actioner
+	steps          int
+	totalReward    float64
+}
+
+// NewSession returns a reference to a new Session for agent.
+func NewSession(agent iface.Agenter) *Session {
+	return &Session{agent: agent}
+}
+
+// Step supplies the state that resulted from the previously recommended
+// action, and the reward for that transition. The agent learns from the
+// transition, then recommends the next action for currentState.
+//
+// On the first step of an episode there is no previous transition, so reward
+// is ignored. If currentState is terminal (it implements iface.Terminaler and
+// reports that it is terminal, or it has no possible actions), the episode
+// ends after the agent learns, and Step returns a nil action. The next call to
+// Step begins a new episode.
+//
+// If the agent fails to recommend an action, the error is returned and the
+// session forgets the previous state, so the next Step does not learn. An
+// error is also returned if currentState is nil, in which case nothing is
+// learned and the session is unchanged.
+func (s *Session) Step(currentState iface.Stater, reward float64) (iface.Actioner, error) {
+	if currentState == nil {
+		return nil, fmt.Errorf("currentState must not be nil")
+	}
+	if s.previousState != nil {
+		s.agent.Learn(s.previousState, s.previousAction, currentState, reward)
+		s.steps++
+		s.totalReward += reward
+	}
+
+	if isTerminal(currentState) {
+		s.Reset()
+		return nil, nil
+	}
+
+	action, err := s.agent.RecommendAction(currentState)
+	if err != nil {
+		s.previousState, s.previousAction = nil, nil
+		return nil, err
+	}
+	s.previousState, s.previousAction = currentState, action
+	return action, nil
+}
+
+// End ends the episode early, such as when it is truncated by a time limit.
+// The agent learns from the transition to finalState as usual, but no action
+// is recommended for it, and the next call to Step begins a new episode.
+// An error is returned if finalState is nil, in which case nothing is learned
+// and the session is unchanged.
+func (s *Session) End(finalState iface.Stater, reward float64) error {
+	if finalState == nil {
+		return fmt.Errorf("finalState must not be nil")
+	}
+	if s.previousState != nil {
+		s.agent.Learn(s.previousState, s.previousAction, finalState, reward)
+		s.steps++
+		s.totalReward += reward
+	}
+	s.Reset()
+	return nil
+}
+
+// Reset abandons the current episode without learning from it.
+func (s *Session) Reset() {
+	s.previousState, s.previousAction = nil, nil
+}
+
+// InEpisode reports whether an episode is underway, in which case the next
+// call to Step learns from the previous transition.
+func (s *Session) InEpisode() bool {
+	return s.previousState != nil
+}
+
+// Steps returns the number of transitions learned from across all episodes.
+func (s *Session) Steps() int {
+	return s.steps
+}
+
+// TotalReward returns the sum of the rewards learned from across all
+// episodes.
+func (s *Session) TotalReward() float64 {
+	return s.totalReward
+}
+
+func isTerminal(state iface.Stater) bool {
+	if t, ok := state.(iface.Terminaler); ok && t.IsTerminal() {
+		return true
+	}
+	return len(state.PossibleActions()) == 0
+}
@@ -0,0 +1,129 @@
+package qlearning_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// transition is a call to Learn recorded by recordingAgent.
+type transition struct {
+	previous, action, current string
+	reward                    float64
+}
+
+// recordingAgent is an iface.Agenter that recommends the first possible
+// action of each state, and records every transition it learns from.
+type recordingAgent struct {
+	transitions []transition
+	fail        bool
+}
+
+func (r *recordingAgent) RecommendAction(state iface.Stater) (iface.Actioner, error) {
+	if r.fail {
+		return nil, fmt.Errorf("no recommendation")
+	}
+	return state.PossibleActions()[0], nil
+}
+
+func (r *recordingAgent) Transition(state iface.Stater, action iface.Actioner) error {
+	return state.Apply(action)
+}
+
+func (r *recordingAgent) Learn(previous iface.Stater, action iface.Actioner, current iface.Stater, reward float64) {
+	r.transitions = append(r.transitions, transition{previous.ID(), action.ID(), current.ID(), reward})
+}
+
+func Test_Session(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	_, _, a, b, terminal := newPriorFixture(mc)
+
+	agent := &recordingAgent{}
+	session := qlearning.NewSession(agent)
+
+	action, err := session.Step(a, 100)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "X", action.ID())
+	}
+	assert.Empty(t, agent.transitions, "the first step is a bootstrap")
+	assert.True(t, session.InEpisode())
+
+	_, err = session.Step(b, 1)
+	assert.NoError(t, err)
+	action, err = session.Step(terminal, 2)
+	assert.NoError(t, err)
+	assert.Nil(t, action)
+	assert.False(t, session.InEpisode())
+
+	// A new episode, ended early.
+	_, err = session.Step(b, 100)
+	assert.NoError(t, err)
+	assert.NoError(t, session.End(a, 3))
+
+	assert.Equal(t, []transition{
+		{"A", "X", "B", 1},
+		{"B", "X", "T", 2},
+		{"B", "X", "A", 3},
+	}, agent.transitions)
+	assert.Equal(t, 3, session.Steps())
+	assert.Equal(t, 6.0, session.TotalReward())
+}
+
+func Test_SessionRecommendationFailure(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	_, _, a, b, _ := newPriorFixture(mc)
+
+	agent := &recordingAgent{}
+	session := qlearning.NewSession(agent)
+	_, err := session.Step(a, 0)
+	assert.NoError(t, err)
+
+	agent.fail = true
+	_, err = session.Step(b, 1)
+	assert.Error(t, err)
+	assert.False(t, session.InEpisode())
+
+	agent.fail = false
+	_, err = session.Step(a, 1)
+	assert.NoError(t, err)
+	assert.Len(t, agent.transitions, 1, "no transition is learned across the failure")
+}
+
+func Test_SessionStepNilState(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	_, a, _ := newRecommendFixture(mc, "X")
+
+	agent := &recordingAgent{}
+	session := qlearning.NewSession(agent)
+	_, err := session.Step(a, 0)
+	assert.NoError(t, err)
+
+	action, err := session.Step(nil, 1)
+	assert.Nil(t, action)
+	assert.EqualError(t, err, "currentState must not be nil")
+	assert.True(t, session.InEpisode())
+	assert.Empty(t, agent.transitions)
+}
+
+func Test_SessionEndNilState(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	_, a, _ := newRecommendFixture(mc, "X")
+
+	agent := &recordingAgent{}
+	session := qlearning.NewSession(agent)
+	_, err := session.Step(a, 0)
+	assert.NoError(t, err)
+
+	assert.EqualError(t, session.End(nil, 1), "finalState must not be nil")
+	assert.True(t, session.InEpisode())
+	assert.Empty(t, agent.transitions)
+	assert.Equal(t, 0, session.Steps())
+}
@@ -0,0 +1,65 @@
+package qlearning
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+)
+
+// Source is a seedable pseudo-random rand.Source whose internal state can be
+// captured and restored via MarshalBinary and UnmarshalBinary. This allows an
+// agent that has been restored from a snapshot to continue the same random
+// sequence that it would have produced had it never been interrupted.
+//
+// Source implements the SplitMix64 generator, which is fast, has a single
+// 64 bit word of state, and is more than adequate for tie-breaking and
+// exploration. It is not suitable for cryptographic purposes.
+// see https://prng.di.unimi.it/splitmix64.c
+type Source struct {
+	state uint64
+}
+
+// NewSource returns a reference to a new Source initialized with seed.
+func NewSource(seed int64) *Source {
+	s := new(Source)
+	s.Seed(seed)
+	return s
+}
+
+// Seed resets the Source to the beginning of the sequence for seed.
+func (s *Source) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+// Uint64 returns a pseudo-random 64 bit value.
+func (s *Source) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Int63 returns a non-negative pseudo-random 63 bit integer.
+func (s *Source) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// MarshalBinary returns the internal state of the Source.
+func (s *Source) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, s.state)
+	return b, nil
+}
+
+// UnmarshalBinary restores the internal state of the Source from the output
+// of MarshalBinary.
+func (s *Source) UnmarshalBinary(b []byte) error {
+	if len(b) != 8 {
+		return fmt.Errorf("invalid source state: expected 8 bytes, got %v", len(b))
+	}
+	s.state = binary.BigEndian.Uint64(b)
+	return nil
+}
+
+var _ rand.Source64 = (*Source)(nil)
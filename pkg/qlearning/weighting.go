@@ -0,0 +1,212 @@
+package qlearning
+
+import (
+	"math"
+	"time"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	qlmath "github.com/eltorocorp/reinforcement-learning/pkg/qlearning/internal/math"
+)
+
+// WeightInput describes an action whose raw q-value is to be weighted.
+type WeightInput struct {
+	// PrimingThreshold is the agent's priming threshold.
+	PrimingThreshold float64
+	// Calls is the number of times the action has been called, or its
+	// effective number of calls if the agent forgets (see Forgetting).
+	Calls float64
+	// Prior is the action's prior q-value (see Prior).
+	Prior float64
+	// Raw is the action's raw q-value.
+	Raw float64
+	// Stats are the action's stats.
+	Stats iface.ActionStatter
+	// Siblings are the stats of every action recorded for the state, keyed by
+	// action ID, including the action being weighted.
+	Siblings map[string]iface.ActionStatter
+}
+
+// A Weighter combines an action's raw q-value with its prior to produce the
+// weighted q-value by which the agent compares actions.
+type Weighter interface {
+	Weight(in WeightInput) float64
+}
+
+// UpdateBaser is an optional interface that a Weighter may implement to
+// determine the q-value from which Learn updates an action. UpdateBase is
+// supplied the same input as Weight. If the agent's Weighter does not
+// implement UpdateBaser (as is the case for a WeighterFunc), Learn updates
+// each action from its raw q-value, and the weighted q-value only serves to
+// compare actions. A Weighter that wraps another should implement UpdateBaser
+// if the wrapped Weighter does.
+type UpdateBaser interface {
+	UpdateBase(in WeightInput) float64
+}
+
+// WeighterFunc adapts an ordinary function to the Weighter interface.
+type WeighterFunc func(in WeightInput) float64
+
+// Weight calls f(in).
+func (f WeighterFunc) Weight(in WeightInput) float64 {
+	return f(in)
+}
+
+// BayesianAverageWeighter returns a Weighter that applies a Bayesian average,
+// in which the prior carries the weight of PrimingThreshold observations.
+// This is the BayesianAgent's default Weighter.
+//
+// The Weighter is an UpdateBaser, under which Learn updates each action from
+// its weighted q-value, so that the prior's influence carries into the
+// action's raw q-value.
+// see https://en.wikipedia.org/wiki/Bayesian_average
+func BayesianAverageWeighter() Weighter {
+	return bayesianAverageWeighter{}
+}
+
+type bayesianAverageWeighter struct{}
+
+func (bayesianAverageWeighter) Weight(in WeightInput) float64 {
+	return qlmath.BayesianAverage(in.PrimingThreshold, in.Calls, in.Prior, in.Raw)
+}
+
+func (w bayesianAverageWeighter) UpdateBase(in WeightInput) float64 {
+	return w.Weight(in)
+}
+
+// updateBase returns the q-value from which Learn updates an action of a
+// state, as determined by the agent's Weighter (see UpdateBaser). siblings
+// are the stats of the state's recorded actions.
+func (a *BayesianAgent) updateBase(state iface.Stater, actionID string, stats iface.ActionStatter, siblings map[string]iface.ActionStatter, now time.Time) float64 {
+	baser, ok := a.weighter.(UpdateBaser)
+	if !ok {
+		return nanToZero(stats.QValueRaw())
+	}
+	if _, found := siblings[actionID]; !found {
+		withAction := make(map[string]iface.ActionStatter, len(siblings)+1)
+		for id, sibling := range siblings {
+			withAction[id] = sibling
+		}
+		withAction[actionID] = stats
+		siblings = withAction
+	}
+	return baser.UpdateBase(WeightInput{
+		PrimingThreshold: float64(a.primingThreshold),
+		Calls:            a.effectiveCalls(stats, now),
+		Prior:            nanToZero(a.prior.Estimate(a.store, state, actionID)),
+		Raw:              nanToZero(stats.QValueRaw()),
+		Stats:            stats,
+		Siblings:         siblings,
+	})
+}
+
+// PassthroughWeighter returns a Weighter that applies no weighting, such that
+// actions are compared by their raw q-values alone.
+func PassthroughWeighter() Weighter {
+	return WeighterFunc(func(in WeightInput) float64 {
+		return in.Raw
+	})
+}
+
+// WilsonLowerBoundWeighter returns a Weighter suited to Bernoulli rewards
+// (such as clicks or conversions), where the raw q-value of an action is the
+// observed success rate. The weighted q-value is the lower bound of the Wilson
+// score interval for the success rate, with z standard deviations of
+// confidence (1.96 for 95%). Actions with few calls are thus penalized in
+// proportion to the uncertainty of their success rate. Raw values are clamped
+// to [0, 1]. The prior is not used.
+//
+// For the raw q-value to be a success rate, the agent should be configured
+// with a discount factor of 0 and a VisitDecay(1, 1, 0) learning rate.
+// see https://en.wikipedia.org/wiki/Binomial_proportion_confidence_interval#Wilson_score_interval
+func WilsonLowerBoundWeighter(z float64) Weighter {
+	return WeighterFunc(func(in WeightInput) float64 {
+		n := in.Calls
+		if n <= 0 {
+			return 0
+		}
+		p := math.Min(math.Max(in.Raw, 0), 1)
+		z2 := z * z
+		center := p + z2/(2*n)
+		margin := z * math.Sqrt(p*(1-p)/n+z2/(4*n*n))
+		return (center - margin) / (1 + z2/n)
+	})
+}
+
+// EmpiricalBayesWeighter returns a Weighter that applies a Bayesian average
+// whose priming threshold is estimated from the observed data rather than
+// configured. The threshold is the ratio of the variance of individual
+// observations of an action (σ²) to the variance of the true values of a
+// state's actions (τ²). When observations are noisy relative to the spread
+// between actions, the prior is trusted for longer.
+//
+// σ² is the mean variance reported by the state's actions, which requires
+// stats that implement iface.VarianceStatter, as ActionStats does. τ² is
+// estimated from the spread of the raw q-values of the state's called actions,
+// less the portion of that spread attributable to σ². If either cannot be
+// estimated, the agent's configured priming threshold is used.
+//
+// Like BayesianAverageWeighter, the Weighter is an UpdateBaser under which
+// Learn updates each action from its weighted q-value.
+// see https://en.wikipedia.org/wiki/Empirical_Bayes_method
+func EmpiricalBayesWeighter() Weighter {
+	return empiricalBayesWeighter{}
+}
+
+type empiricalBayesWeighter struct{}
+
+func (empiricalBayesWeighter) Weight(in WeightInput) float64 {
+	threshold := in.PrimingThreshold
+	if estimated, ok := estimateThreshold(in.Siblings); ok {
+		threshold = estimated
+	}
+	return qlmath.BayesianAverage(threshold, in.Calls, in.Prior, in.Raw)
+}
+
+func (w empiricalBayesWeighter) UpdateBase(in WeightInput) float64 {
+	return w.Weight(in)
+}
+
+// estimateThreshold returns σ²/τ² for a state's actions, or false if either
+// variance cannot be estimated.
+func estimateThreshold(siblings map[string]iface.ActionStatter) (float64, bool) {
+	var raws []float64
+	withinSum := 0.0
+	noiseSum := 0.0
+	for _, stats := range siblings {
+		v, ok := stats.(iface.VarianceStatter)
+		if !ok || stats.Calls() < 2 {
+			continue
+		}
+		variance := nanToZero(v.Variance())
+		raws = append(raws, nanToZero(stats.QValueRaw()))
+		withinSum += variance
+		noiseSum += variance / float64(stats.Calls())
+	}
+	if len(raws) < 2 {
+		return 0, false
+	}
+
+	k := float64(len(raws))
+	mean := 0.0
+	for _, r := range raws {
+		mean += r
+	}
+	mean /= k
+	between := 0.0
+	for _, r := range raws {
+		between += (r - mean) * (r - mean)
+	}
+	between /= k - 1
+
+	sigma2 := withinSum / k
+	tau2 := between - noiseSum/k
+	if !(tau2 > 0) || sigma2 == 0 {
+		return 0, false
+	}
+	return sigma2 / tau2, true
+}
+
+var (
+	_ UpdateBaser = bayesianAverageWeighter{}
+	_ UpdateBaser = empiricalBayesWeighter{}
+)
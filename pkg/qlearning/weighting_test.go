@@ -0,0 +1,144 @@
+package qlearning_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning"
+	"github.com/eltorocorp/reinforcement-learning/pkg/qlearning/iface"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PassthroughWeighter(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, _, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithLearningRate(1),
+		qlearning.WithDiscount(0),
+		qlearning.WithPrior(qlearning.FixedPrior(10)),
+		qlearning.WithWeighter(qlearning.PassthroughWeighter()),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ba.Learn(a, x, terminal, 2)
+
+	assert.Equal(t, map[string]float64{"X": 2, "Y": 0}, weightedValues(ba, "A"))
+}
+
+func Test_WilsonLowerBoundWeighter(t *testing.T) {
+	w := qlearning.WilsonLowerBoundWeighter(1.96)
+
+	assert.Equal(t, 0.0, w.Weight(qlearning.WeightInput{Calls: 0, Raw: 1}))
+
+	few := w.Weight(qlearning.WeightInput{Calls: 10, Raw: .5})
+	many := w.Weight(qlearning.WeightInput{Calls: 1000, Raw: .5})
+	assert.InDelta(t, .2366, few, 1e-4)
+	assert.True(t, few < many && many < .5)
+
+	clamped := w.Weight(qlearning.WeightInput{Calls: 10, Raw: 1.5})
+	assert.Equal(t, w.Weight(qlearning.WeightInput{Calls: 10, Raw: 1}), clamped)
+}
+
+func Test_EmpiricalBayesWeighter(t *testing.T) {
+	w := qlearning.EmpiricalBayesWeighter()
+	x := &qlearning.ActionStats{}
+	y := &qlearning.ActionStats{}
+	for _, target := range []float64{-2, -2, 2, 2} {
+		x.Observe(0, target)
+		y.Observe(0, target+4)
+	}
+	x.SetCalls(4)
+	y.SetCalls(4)
+	y.SetQValueRaw(4)
+	siblings := map[string]iface.ActionStatter{"X": x, "Y": y}
+
+	// σ² = 16/3, τ² = 8 - (16/3)/4 = 20/3, so the threshold is 4/5.
+	in := qlearning.WeightInput{
+		PrimingThreshold: 100,
+		Calls:            4,
+		Prior:            2,
+		Raw:              4,
+		Stats:            y,
+		Siblings:         siblings,
+	}
+	weighted := w.Weight(in)
+	threshold := 4.0 / 5
+	assert.InDelta(t, (threshold*2+4*4)/(threshold+4), weighted, 1e-9)
+	if baser, ok := w.(qlearning.UpdateBaser); assert.True(t, ok) {
+		assert.Equal(t, weighted, baser.UpdateBase(in))
+	}
+
+	// Without variance, the configured threshold is used.
+	fallback := w.Weight(qlearning.WeightInput{
+		PrimingThreshold: 4,
+		Calls:            4,
+		Prior:            2,
+		Raw:              4,
+	})
+	assert.Equal(t, 3.0, fallback)
+	assert.False(t, math.IsNaN(fallback))
+}
+
+func Test_WithWeighterNil(t *testing.T) {
+	_, err := qlearning.NewBayesianAgentWithOptions(qlearning.WithWeighter(nil))
+	assert.Error(t, err)
+}
+
+func Test_WilsonLowerBoundWeighterLearnsSuccessRate(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, y, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithDiscount(0),
+		qlearning.WithLearningRateSchedule(qlearning.VisitDecay(1, 1, 0)),
+		qlearning.WithWeighter(qlearning.WilsonLowerBoundWeighter(1.96)),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 100; i++ {
+		ba.Learn(a, x, terminal, 1)
+		ba.Learn(a, y, terminal, float64(i%4/3))
+	}
+
+	// The raw q-values are the observed success rates, and the weighted
+	// q-values are the lower bounds of their Wilson score intervals.
+	stats := ba.GetAgentContext().QValues["A"]
+	assert.InDelta(t, 1, stats["X"].QValueRaw(), 1e-9)
+	assert.InDelta(t, .25, stats["Y"].QValueRaw(), 1e-9)
+	assert.InDelta(t, 1/(1+1.96*1.96/100), stats["X"].QValueWeighted(), 1e-9)
+	assert.InDelta(t, .1754, stats["Y"].QValueWeighted(), 1e-4)
+}
+
+func Test_EmpiricalBayesWeighterUsesActionStatsVariance(t *testing.T) {
+	mc := gomock.NewController(t)
+	defer mc.Finish()
+	x, y, a, _, terminal := newPriorFixture(mc)
+
+	ba, err := qlearning.NewBayesianAgentWithOptions(
+		qlearning.WithPrimingThreshold(1000),
+		qlearning.WithDiscount(0),
+		qlearning.WithLearningRateSchedule(qlearning.VisitDecay(1, 1, 0)),
+		qlearning.WithWeighter(qlearning.EmpiricalBayesWeighter()),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 20; i++ {
+		ba.Learn(a, x, terminal, float64(i%2))
+		ba.Learn(a, y, terminal, float64(10+i%2))
+	}
+
+	// The variance recorded by the default ActionStats yields a small
+	// estimated threshold, so the weighted q-values stay close to the raw
+	// q-values despite the large configured priming threshold.
+	stats := ba.GetAgentContext().QValues["A"]
+	assert.InDelta(t, stats["X"].QValueRaw(), stats["X"].QValueWeighted(), .01)
+	assert.InDelta(t, stats["Y"].QValueRaw(), stats["Y"].QValueWeighted(), .01)
+	assert.True(t, stats["Y"].QValueWeighted()-stats["X"].QValueWeighted() > 9)
+}